@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+)
+
+const adminUsage = `Usage: app admin <command> [flags] [user]
+
+Commands:
+  create          create a user holding the admin role
+  grant <user>    grant the admin role to an existing user
+  revoke <user>   revoke the admin role
+  reset-password <user>
+                  set a new password and sign the user out everywhere
+  list            list admins
+
+<user> is a user ID, email or username. Flags go before it. All commands
+take -config (default ./configs/example.yaml) and use the database of that
+config. Passwords are read from the first line of stdin with
+-password-stdin, otherwise one is generated and printed once.
+`
+
+const defaultConfigPath = "./configs/example.yaml"
+
+// cliDetails marks audit events caused by the admin commands, they have no
+// actor.
+var cliDetails = map[string]any{"via": "cli"}
+
+func runAdmin(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprint(os.Stderr, adminUsage)
+		return nil
+	}
+
+	commands := map[string]func([]string) error{
+		"create":         adminCreate,
+		"grant":          adminGrant,
+		"revoke":         adminRevoke,
+		"reset-password": adminResetPassword,
+		"list":           adminList,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, adminUsage)
+		return fmt.Errorf("unknown admin command %q", args[0])
+	}
+	return command(args[1:])
+}
+
+func adminCreate(args []string) error {
+	fs, configPath := adminFlags("create")
+	username := fs.String("username", "", "username (required)")
+	email := fs.String("email", "", "email (required)")
+	firstName := fs.String("first-name", "", "first name")
+	lastName := fs.String("last-name", "", "last name")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin")
+	fs.Parse(args)
+	if *username == "" || *email == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("-username and -email are required")
+	}
+
+	password, generated, err := readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	a, err := newApp(*configPath)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	userID, err := a.authProvider.CreateAdmin(ctx, auth.RegisterUserData{
+		Username:  *username,
+		Email:     *email,
+		Password:  password,
+		FirstName: *firstName,
+		LastName:  *lastName,
+	})
+	if err != nil {
+		return err
+	}
+	a.authProvider.RecordAuditEvent(ctx, auth.AuditEvent{
+		SubjectID: userID,
+		Action:    auth.AuditRoleGranted,
+		Details:   withCLIDetails(map[string]any{"role": "admin", "scope_type": auth.ScopeGlobal, "created": true}),
+	})
+
+	fmt.Printf("created admin %s (%s)\n", *username, userID)
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return nil
+}
+
+func adminGrant(args []string) error {
+	fs, configPath := adminFlags("grant")
+	expires := fs.Duration("expires", 0, "revoke automatically after this long, e.g. 720h")
+	fs.Parse(args)
+	a, userID, err := adminTarget(fs, *configPath)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	var expiresAt time.Time
+	details := map[string]any{"role": "admin", "scope_type": auth.ScopeGlobal}
+	if *expires > 0 {
+		expiresAt = time.Now().Add(*expires)
+		details["expires_at"] = expiresAt
+	}
+
+	err = a.authProvider.GrantRole(ctx, userID, "admin", auth.RoleScope{Type: auth.ScopeGlobal}, "", expiresAt)
+	if err != nil {
+		return err
+	}
+	a.authProvider.RecordAuditEvent(ctx, auth.AuditEvent{
+		SubjectID: userID,
+		Action:    auth.AuditRoleGranted,
+		Details:   withCLIDetails(details),
+	})
+
+	fmt.Printf("granted admin to %s\n", userID)
+	return nil
+}
+
+func adminRevoke(args []string) error {
+	fs, configPath := adminFlags("revoke")
+	reason := fs.String("reason", "", "why the role is revoked, recorded in the audit log (required)")
+	force := fs.Bool("force", false, "revoke even from the last active admin")
+	fs.Parse(args)
+	if strings.TrimSpace(*reason) == "" {
+		fs.Usage()
+		return errors.New("-reason is required")
+	}
+	a, userID, err := adminTarget(fs, *configPath)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if !*force {
+		admins, err := a.authProvider.GetRoleMembers(ctx, "admin")
+		if err != nil {
+			return err
+		}
+		remaining := 0
+		for _, admin := range admins {
+			if admin.Active && admin.UserID != userID {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			return errors.New("refusing to revoke the last active admin, use -force")
+		}
+	}
+
+	scope := auth.RoleScope{Type: auth.ScopeGlobal}
+	if _, err := a.authProvider.RevokeRole(ctx, userID, "admin", &scope); err != nil {
+		return err
+	}
+	a.authProvider.RecordAuditEvent(ctx, auth.AuditEvent{
+		SubjectID: userID,
+		Action:    auth.AuditRoleRevoked,
+		Details:   withCLIDetails(map[string]any{"role": "admin", "scope_type": auth.ScopeGlobal, "reason": *reason}),
+	})
+
+	fmt.Printf("revoked admin from %s\n", userID)
+	return nil
+}
+
+func adminResetPassword(args []string) error {
+	fs, configPath := adminFlags("reset-password")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin")
+	fs.Parse(args)
+
+	password, generated, err := readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	a, userID, err := adminTarget(fs, *configPath)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if err := a.authProvider.ResetPassword(ctx, userID, password); err != nil {
+		return err
+	}
+	a.authProvider.RecordAuditEvent(ctx, auth.AuditEvent{
+		SubjectID: userID,
+		Action:    auth.AuditPasswordReset,
+		Details:   withCLIDetails(nil),
+	})
+
+	fmt.Printf("reset the password of %s, all sessions were revoked\n", userID)
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return nil
+}
+
+func adminList(args []string) error {
+	fs, configPath := adminFlags("list")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("list takes no arguments")
+	}
+
+	a, err := newApp(*configPath)
+	if err != nil {
+		return err
+	}
+
+	admins, err := a.authProvider.GetRoleMembers(context.Background(), "admin")
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tACTIVE\tGRANTED AT\tGRANTED BY\tEXPIRES AT")
+	for _, admin := range admins {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\t%s\n", admin.UserID, admin.Username, admin.Email,
+			admin.Active, admin.GrantedAt, orDash(admin.GrantedBy), orDash(admin.ExpiresAt))
+	}
+	return w.Flush()
+}
+
+func adminFlags(command string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("admin "+command, flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "config file")
+	return fs, configPath
+}
+
+// adminTarget connects to the database and resolves the single user argument.
+func adminTarget(fs *flag.FlagSet, configPath string) (app, string, error) {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return app{}, "", errors.New("exactly one user ID, email or username is required")
+	}
+
+	a, err := newApp(configPath)
+	if err != nil {
+		return app{}, "", err
+	}
+
+	userID, err := a.authProvider.FindUser(context.Background(), fs.Arg(0))
+	if err != nil {
+		return app{}, "", fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+	return a, userID, nil
+}
+
+// readPassword takes the first line of stdin, or generates a password when
+// fromStdin is false. Passwords are never taken from arguments, those end up
+// in the shell history and process list.
+func readPassword(fromStdin bool) (string, bool, error) {
+	if !fromStdin {
+		secret := make([]byte, 18)
+		if _, err := rand.Read(secret); err != nil {
+			return "", false, err
+		}
+		return base64.RawURLEncoding.EncodeToString(secret), true, nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", false, fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), false, nil
+}
+
+func withCLIDetails(details map[string]any) map[string]any {
+	result := make(map[string]any, len(details)+len(cliDetails))
+	for name, value := range details {
+		result[name] = value
+	}
+	for name, value := range cliDetails {
+		result[name] = value
+	}
+	return result
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
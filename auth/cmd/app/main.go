@@ -1,39 +1,140 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/vladlim/auth-service-practice/auth/internal/clients/mailer"
 	"github.com/vladlim/auth-service-practice/auth/internal/config"
+	"github.com/vladlim/auth-service-practice/auth/internal/jobs"
+	"github.com/vladlim/auth-service-practice/auth/internal/policy"
 	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/mfa"
 	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
 	"github.com/vladlim/auth-service-practice/auth/internal/repository/facade"
 	"github.com/vladlim/auth-service-practice/auth/internal/repository/storage"
 	"github.com/vladlim/auth-service-practice/auth/internal/server"
+	"github.com/vladlim/auth-service-practice/auth/internal/validation"
 )
 
+const usage = `Usage:
+  app <config>                     start the server, same as "app serve <config>"
+  app serve <config>               start the server
+  app admin <command> [flags]      manage admins, see "app admin -h"
+`
+
 func main() {
-	configPath := os.Args[1]
+	args := os.Args[1:]
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "serve":
+		fs := flag.NewFlagSet("serve", flag.ExitOnError)
+		fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		serve(fs.Arg(0))
+	case "admin":
+		if err := runAdmin(args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stderr, usage)
+	default:
+		serve(args[0])
+	}
+}
+
+// app is what both the server and the admin commands run on.
+type app struct {
+	conf         config.Config
+	facade       facade.Facade
+	policies     *policy.Engine
+	authProvider auth.AuthProvider
+}
+
+func newApp(configPath string) (app, error) {
 	conf, err := config.Parse(configPath)
+	if err != nil {
+		return app{}, err
+	}
+
+	storage, err := storage.New(conf.DB.GetDBURL(), conf.DB.MigrationsPath)
+	if err != nil {
+		return app{}, err
+	}
+
+	facade := facade.New(storage)
+
+	mailer, err := mailer.New(conf.Clients.Mailer)
+	if err != nil {
+		return app{}, err
+	}
+
+	validator, err := validation.New(conf.Security)
+	if err != nil {
+		return app{}, err
+	}
+
+	policies, err := policy.NewEngine(conf.Security.Policies.Path)
+	if err != nil {
+		return app{}, err
+	}
+
+	return app{
+		conf:         conf,
+		facade:       facade,
+		policies:     policies,
+		authProvider: auth.New(facade, mailer, validator, policies, conf),
+	}, nil
+}
+
+func serve(configPath string) {
+	a, err := newApp(configPath)
 	if err != nil {
 		panic(err)
 	}
+	conf, facade := a.conf, a.facade
 
 	if err := tokens.InitJWT(conf.AccessSecret, conf.RefreshSecret); err != nil {
 		log.Default().Printf("[ERR] Init jwt parse error: %s\n", err.Error())
 		panic(err)
 	}
 
-	storage, err := storage.New(conf.DB.GetDBURL(), conf.DB.MigrationsPath)
-	if err != nil {
-		panic(err)
+	if conflicts, err := facade.GetIdentityConflicts(context.Background()); err != nil {
+		log.Default().Printf("[ERR] Check identity conflicts error: %s\n", err.Error())
+	} else if len(conflicts) > 0 {
+		log.Default().Printf("[WARN] %d accounts collide with another account by username or email, see GET /admin/identity-conflicts\n", len(conflicts))
 	}
 
-	facade := facade.New(storage)
+	go a.policies.Watch(context.Background(), conf.Security.Policies.ReloadInterval)
 
-	authProvider := auth.New(facade)
+	authProvider := a.authProvider
 	tokensProvider := tokens.New(facade)
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          conf.MFA.WebAuthn.RPID,
+		RPDisplayName: conf.MFA.WebAuthn.RPDisplayName,
+		RPOrigins:     conf.MFA.WebAuthn.RPOrigins,
+	})
+	if err != nil {
+		panic(err)
+	}
+	mfaProvider := mfa.New(facade, conf.MFA.Issuer, webAuthn, conf.MFA.RequiredRoles)
+
+	go jobs.NewErasure(facade, conf.Security.Retention).Run(context.Background())
+	go jobs.NewRoleExpiry(authProvider, conf.Security.Roles).Run(context.Background())
 
-	s := server.New(conf, authProvider, tokensProvider)
+	s := server.New(conf, authProvider, tokensProvider, mfaProvider)
 	panic(s.Start())
 }
@@ -3,11 +3,24 @@ module github.com/vladlim/auth-service-practice/auth
 go 1.24.2
 
 require (
+	github.com/go-webauthn/webauthn v0.13.4
 	github.com/jmoiron/sqlx v1.4.0
+	github.com/pquerna/otp v1.5.0
 	github.com/vladlim/utils/db/psql v0.0.0-20250716173528-04e9866b8208
 	gopkg.in/yaml.v3 v3.0.1
 )
 
+require (
+	github.com/boombuler/barcode v1.0.1-0.20190219062509-6c824513bacc // indirect
+	github.com/fxamacker/cbor/v2 v2.9.0 // indirect
+	github.com/go-webauthn/x v0.1.23 // indirect
+	github.com/google/go-tpm v0.9.5 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mitchellh/mapstructure v1.5.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+)
+
 require (
 	github.com/golang-jwt/jwt/v5 v5.2.3
 	github.com/golang-migrate/migrate/v4 v4.18.3 // indirect
@@ -16,9 +29,10 @@ require (
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/lib/pq v1.10.9
 	go.uber.org/atomic v1.7.0 // indirect
-	golang.org/x/crypto v0.36.0
+	golang.org/x/crypto v0.40.0
+	golang.org/x/text v0.27.0
 	golang.org/x/oauth2 v0.18.0 // indirect
 	google.golang.org/appengine v1.6.8 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/config"
+)
+
+const (
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers plain-text emails. The implementation is picked by the
+// clients.mailer.driver config value.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func New(conf config.Mailer) (Mailer, error) {
+	switch conf.Driver {
+	case "", DriverLog:
+		return LogMailer{}, nil
+	case DriverSMTP:
+		return SMTPMailer{conf: conf}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer driver: %s", conf.Driver)
+	}
+}
+
+// LogMailer writes messages to the log instead of sending them. Meant for
+// local development.
+type LogMailer struct{}
+
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	log.Default().Printf("[MAIL] to=%s subject=%q\n%s\n", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+type SMTPMailer struct {
+	conf config.Mailer
+}
+
+func (m SMTPMailer) Send(_ context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.conf.Host, strconv.Itoa(int(m.conf.Port)))
+
+	var auth smtp.Auth
+	if m.conf.Username != "" {
+		auth = smtp.PlainAuth("", m.conf.Username, m.conf.Password, m.conf.Host)
+	}
+
+	var body strings.Builder
+	body.WriteString("From: " + m.conf.From + "\r\n")
+	body.WriteString("To: " + msg.To + "\r\n")
+	body.WriteString("Subject: " + msg.Subject + "\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(msg.Body)
+
+	return smtp.SendMail(addr, auth, m.conf.From, []string{msg.To}, []byte(body.String()))
+}
@@ -2,20 +2,124 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/vladlim/utils/db/psql"
 	"gopkg.in/yaml.v3"
 )
 
-type Clients struct{}
+type Mailer struct {
+	Driver   string `yaml:"driver"`
+	Host     string `yaml:"host"`
+	Port     uint16 `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+type Clients struct {
+	Mailer Mailer `yaml:"mailer"`
+}
+
+type Lockout struct {
+	MaxFailures int           `yaml:"max_failures"`
+	Window      time.Duration `yaml:"window"`
+}
+
+type RateLimit struct {
+	Limit  int           `yaml:"limit"`
+	Window time.Duration `yaml:"window"`
+}
+
+type EmailLogin struct {
+	Enabled    bool          `yaml:"enabled"`
+	LinkURL    string        `yaml:"link_url"`
+	TTL        time.Duration `yaml:"ttl"`
+	PerAddress RateLimit     `yaml:"per_address"`
+	PerIP      RateLimit     `yaml:"per_ip"`
+}
+
+type EmailChange struct {
+	ConfirmURL string        `yaml:"confirm_url"`
+	CancelURL  string        `yaml:"cancel_url"`
+	TTL        time.Duration `yaml:"ttl"`
+}
+
+type Username struct {
+	ReservationPeriod time.Duration `yaml:"reservation_period"`
+	MinLength         int           `yaml:"min_length"`
+	MaxLength         int           `yaml:"max_length"`
+	Charset           string        `yaml:"charset"`
+	Reserved          []string      `yaml:"reserved"`
+}
+
+type Email struct {
+	DisposableDomainsPath string `yaml:"disposable_domains_path"`
+}
+
+type RegistrationDomain struct {
+	Domain       string `yaml:"domain"`
+	UniversityID string `yaml:"university_id"`
+}
+
+type Registration struct {
+	Mode    string               `yaml:"mode"`
+	Domains []RegistrationDomain `yaml:"domains"`
+}
+
+type Retention struct {
+	DeletedAccounts time.Duration `yaml:"deleted_accounts"`
+	ErasureInterval time.Duration `yaml:"erasure_interval"`
+}
+
+type Roles struct {
+	ExpiryInterval time.Duration `yaml:"expiry_interval"`
+}
+
+// Keys limits the activation keys teachers may issue for their groups.
+type Keys struct {
+	TeacherQuota RateLimit `yaml:"teacher_quota"`
+}
+
+type Policies struct {
+	Path           string        `yaml:"path"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+type Security struct {
+	Lockout      Lockout      `yaml:"lockout"`
+	EmailLogin   EmailLogin   `yaml:"email_login"`
+	EmailChange  EmailChange  `yaml:"email_change"`
+	Username     Username     `yaml:"username"`
+	Email        Email        `yaml:"email"`
+	Registration Registration `yaml:"registration"`
+	Retention    Retention    `yaml:"retention"`
+	Roles        Roles        `yaml:"roles"`
+	Policies     Policies     `yaml:"policies"`
+	Keys         Keys         `yaml:"keys"`
+}
+
+type WebAuthn struct {
+	RPID          string   `yaml:"rp_id"`
+	RPDisplayName string   `yaml:"rp_display_name"`
+	RPOrigins     []string `yaml:"rp_origins"`
+}
+
+type MFA struct {
+	Issuer        string   `yaml:"issuer"`
+	WebAuthn      WebAuthn `yaml:"webauthn"`
+	RequiredRoles []string `yaml:"required_roles"`
+}
 
 // Config ...
 type Config struct {
-	Port          uint16  `yaml:"port"`
-	DB            psql.DB `yaml:"db"`
-	Clients       Clients `yaml:"clients"`
-	AccessSecret  string  `yaml:"access_secret"`
-	RefreshSecret string  `yaml:"refresh_secret"`
+	Port          uint16   `yaml:"port"`
+	DB            psql.DB  `yaml:"db"`
+	Clients       Clients  `yaml:"clients"`
+	AccessSecret  string   `yaml:"access_secret"`
+	RefreshSecret string   `yaml:"refresh_secret"`
+	MFA           MFA      `yaml:"mfa"`
+	Security      Security `yaml:"security"`
 }
 
 // Parse ...
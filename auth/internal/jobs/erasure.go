@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/config"
+)
+
+const (
+	defaultRetention       = 30 * 24 * time.Hour
+	defaultErasureInterval = time.Hour
+	erasureBatchSize       = 100
+)
+
+type ErasureRepository interface {
+	GetUsersToErase(ctx context.Context, deletedBefore time.Time, limit int) ([]string, error)
+	EraseUser(ctx context.Context, userID string) (bool, error)
+}
+
+// Erasure anonymizes accounts that were soft-deleted longer than the
+// retention period ago.
+type Erasure struct {
+	repository ErasureRepository
+	retention  time.Duration
+	interval   time.Duration
+}
+
+func NewErasure(repository ErasureRepository, conf config.Retention) Erasure {
+	job := Erasure{
+		repository: repository,
+		retention:  conf.DeletedAccounts,
+		interval:   conf.ErasureInterval,
+	}
+	if job.retention <= 0 {
+		job.retention = defaultRetention
+	}
+	if job.interval <= 0 {
+		job.interval = defaultErasureInterval
+	}
+	return job
+}
+
+// Run erases due accounts right away and then on every interval until the
+// context is cancelled.
+func (j Erasure) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j Erasure) runOnce(ctx context.Context) {
+	deletedBefore := time.Now().Add(-j.retention)
+	for {
+		userIDs, err := j.repository.GetUsersToErase(ctx, deletedBefore, erasureBatchSize)
+		if err != nil {
+			log.Default().Println("[ERR] erasure: list users:", err.Error())
+			return
+		}
+
+		for _, userID := range userIDs {
+			if _, err := j.repository.EraseUser(ctx, userID); err != nil {
+				log.Default().Printf("[ERR] erasure: user %s: %s\n", userID, err.Error())
+				// Skip the rest of the run, otherwise a failing user would
+				// come back in every batch.
+				return
+			}
+			log.Default().Println("[ERASURE] erased user:", userID)
+		}
+
+		if len(userIDs) < erasureBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/config"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+)
+
+const (
+	defaultRoleExpiryInterval = 10 * time.Minute
+	roleExpiryBatchSize       = 100
+)
+
+type RoleExpiryProvider interface {
+	ExpireRoles(ctx context.Context, limit int) ([]auth.RoleAssignment, error)
+}
+
+// RoleExpiry removes role assignments whose expires_at has passed. The
+// provider records an audit event for every removed assignment.
+type RoleExpiry struct {
+	provider RoleExpiryProvider
+	interval time.Duration
+}
+
+func NewRoleExpiry(provider RoleExpiryProvider, conf config.Roles) RoleExpiry {
+	job := RoleExpiry{
+		provider: provider,
+		interval: conf.ExpiryInterval,
+	}
+	if job.interval <= 0 {
+		job.interval = defaultRoleExpiryInterval
+	}
+	return job
+}
+
+// Run sweeps expired roles right away and then on every interval until the
+// context is cancelled.
+func (j RoleExpiry) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j RoleExpiry) runOnce(ctx context.Context) {
+	for {
+		expired, err := j.provider.ExpireRoles(ctx, roleExpiryBatchSize)
+		if err != nil {
+			log.Default().Println("[ERR] role expiry:", err.Error())
+			return
+		}
+
+		for _, assignment := range expired {
+			log.Default().Printf("[ROLE EXPIRY] user %s lost role %s (%s %s)\n",
+				assignment.UserID, assignment.Role, assignment.Scope.Type, assignment.Scope.ID)
+		}
+
+		if len(expired) < roleExpiryBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultReloadInterval = 30 * time.Second
+
+// Engine holds the policies loaded from a file and reloads them when the
+// file changes. A file that fails to load keeps the previous policies in
+// place.
+type Engine struct {
+	path string
+
+	mu      sync.RWMutex
+	set     *Set
+	modTime time.Time
+	// failed is the modification time of a file that did not load, so that
+	// the same error is not logged on every tick.
+	failed time.Time
+}
+
+// NewEngine loads the policies at path. Without a path the engine has no
+// policies and never reloads.
+func NewEngine(path string) (*Engine, error) {
+	e := &Engine{path: path}
+	if path == "" {
+		return e, nil
+	}
+
+	if _, err := e.reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Policies returns the current set. It may be nil, which evaluates to no
+// effect.
+func (e *Engine) Policies() *Set {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.set
+}
+
+// Watch checks the file for changes on every interval until the context is
+// cancelled.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	if e.path == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := e.reload()
+		if err != nil {
+			log.Default().Printf("[ERR] policies: reload %s: %s\n", e.path, err.Error())
+			continue
+		}
+		if reloaded {
+			log.Default().Printf("[POLICIES] reloaded %d policies from %s\n", len(e.Policies().Policies), e.path)
+		}
+	}
+}
+
+// reload loads the file if it changed since the last successful load.
+func (e *Engine) reload() (bool, error) {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return false, err
+	}
+
+	e.mu.RLock()
+	unchanged := e.set != nil && info.ModTime().Equal(e.modTime) || info.ModTime().Equal(e.failed)
+	e.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	set, err := Load(e.path)
+	if err != nil {
+		e.mu.Lock()
+		e.failed = info.ModTime()
+		e.mu.Unlock()
+		return false, err
+	}
+
+	e.mu.Lock()
+	e.set, e.modTime, e.failed = set, info.ModTime(), time.Time{}
+	e.mu.Unlock()
+	return true, nil
+}
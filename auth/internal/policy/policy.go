@@ -0,0 +1,255 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Attributes describe the subject or the resource of a decision. Values are
+// strings or string lists; empty values are unknown and never match.
+type Attributes map[string]any
+
+// Known attributes. Conditions may only refer to these, so that typos are
+// caught when the file is loaded rather than silently never matching.
+var (
+	subjectAttributes  = []string{"id", "roles", "university_id", "group_id", "groups"}
+	resourceAttributes = []string{"type", "id", "roles", "university_id", "group_id"}
+)
+
+// Policy grants (or denies) the actions when the resource type matches and
+// all conditions hold. An empty resource type matches every resource.
+type Policy struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Effect      string   `yaml:"effect"`
+	Actions     []string `yaml:"actions"`
+	Resource    string   `yaml:"resource"`
+	When        []string `yaml:"when"`
+
+	conditions []condition
+}
+
+type Set struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+// Input is what a policy is evaluated against.
+type Input struct {
+	Action   string
+	Subject  Attributes
+	Resource Attributes
+}
+
+// Evaluation is the outcome over a whole set: the effect and name of the
+// deciding policy, if any, and what every policy concluded.
+type Evaluation struct {
+	Effect string
+	Policy string
+	Trace  []Trace
+}
+
+type Trace struct {
+	Policy     string
+	Effect     string
+	Applies    bool
+	Matched    bool
+	Conditions []ConditionTrace
+}
+
+type ConditionTrace struct {
+	Condition string
+	Result    bool
+}
+
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path) // nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse reads a policy file and compiles its conditions.
+func Parse(data []byte) (*Set, error) {
+	var set Set
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+
+	names := make(map[string]struct{}, len(set.Policies))
+	for i := range set.Policies {
+		p := &set.Policies[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("%w: policy %d has no name", ErrInvalidPolicy, i+1)
+		}
+		if _, ok := names[p.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate policy %q", ErrInvalidPolicy, p.Name)
+		}
+		names[p.Name] = struct{}{}
+
+		if p.Effect == "" {
+			p.Effect = EffectAllow
+		}
+		if p.Effect != EffectAllow && p.Effect != EffectDeny {
+			return nil, fmt.Errorf("%w: policy %q: effect must be allow or deny", ErrInvalidPolicy, p.Name)
+		}
+		if len(p.Actions) == 0 {
+			return nil, fmt.Errorf("%w: policy %q has no actions", ErrInvalidPolicy, p.Name)
+		}
+
+		for _, expr := range p.When {
+			c, err := parseCondition(expr)
+			if err != nil {
+				return nil, fmt.Errorf("%w: policy %q: %w", ErrInvalidPolicy, p.Name, err)
+			}
+			p.conditions = append(p.conditions, c)
+		}
+	}
+
+	return &set, nil
+}
+
+// Evaluate runs every policy against the input. A matching deny policy wins
+// over any allow policy; without a match the effect is empty and the caller
+// falls back to its own decision.
+func (s *Set) Evaluate(input Input) Evaluation {
+	var result Evaluation
+	if s == nil {
+		return result
+	}
+
+	for _, p := range s.Policies {
+		trace := p.evaluate(input)
+		result.Trace = append(result.Trace, trace)
+
+		if !trace.Matched || result.Effect == EffectDeny {
+			continue
+		}
+		if result.Effect == "" || p.Effect == EffectDeny {
+			result.Effect, result.Policy = p.Effect, p.Name
+		}
+	}
+
+	return result
+}
+
+func (p Policy) evaluate(input Input) Trace {
+	trace := Trace{Policy: p.Name, Effect: p.Effect}
+
+	resourceType, _ := input.Resource["type"].(string)
+	trace.Applies = slices.Contains(p.Actions, input.Action) && (p.Resource == "" || p.Resource == resourceType)
+	if !trace.Applies {
+		return trace
+	}
+
+	trace.Matched = true
+	for i, c := range p.conditions {
+		result := c.eval(input)
+		trace.Conditions = append(trace.Conditions, ConditionTrace{Condition: p.When[i], Result: result})
+		trace.Matched = trace.Matched && result
+	}
+
+	return trace
+}
+
+// Conditions...
+
+// condition is "<operand> <operator> <operand>". Operands are attributes such
+// as subject.university_id or double-quoted literals. Operators:
+//
+//	==, !=    both sides are known and (not) equal
+//	contains  the list on the left contains the value on the right
+//	in        the value on the left is in the list on the right
+type condition struct {
+	left, op, right string
+}
+
+var operators = []string{"==", "!=", "contains", "in"}
+
+func parseCondition(expr string) (condition, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 3 {
+		return condition{}, fmt.Errorf("condition %q must be <operand> <operator> <operand>", expr)
+	}
+
+	c := condition{left: fields[0], op: fields[1], right: fields[2]}
+	if !slices.Contains(operators, c.op) {
+		return condition{}, fmt.Errorf("condition %q: unknown operator %q", expr, c.op)
+	}
+	for _, operand := range []string{c.left, c.right} {
+		if err := checkOperand(operand); err != nil {
+			return condition{}, fmt.Errorf("condition %q: %w", expr, err)
+		}
+	}
+
+	return c, nil
+}
+
+func checkOperand(operand string) error {
+	if isLiteral(operand) {
+		return nil
+	}
+
+	scope, name, _ := strings.Cut(operand, ".")
+	switch {
+	case scope == "subject" && slices.Contains(subjectAttributes, name),
+		scope == "resource" && slices.Contains(resourceAttributes, name):
+		return nil
+	default:
+		return fmt.Errorf("unknown attribute %q", operand)
+	}
+}
+
+func isLiteral(operand string) bool {
+	return len(operand) >= 2 && strings.HasPrefix(operand, `"`) && strings.HasSuffix(operand, `"`)
+}
+
+func (c condition) eval(input Input) bool {
+	left, right := resolve(c.left, input), resolve(c.right, input)
+
+	switch c.op {
+	case "==", "!=":
+		l, ok1 := left.(string)
+		r, ok2 := right.(string)
+		if !ok1 || !ok2 || l == "" || r == "" {
+			return false
+		}
+		return (l == r) == (c.op == "==")
+	case "contains":
+		return listContains(left, right)
+	case "in":
+		return listContains(right, left)
+	default:
+		return false
+	}
+}
+
+func resolve(operand string, input Input) any {
+	if isLiteral(operand) {
+		return operand[1 : len(operand)-1]
+	}
+
+	scope, name, _ := strings.Cut(operand, ".")
+	if scope == "subject" {
+		return input.Subject[name]
+	}
+	return input.Resource[name]
+}
+
+func listContains(list, value any) bool {
+	values, ok1 := list.([]string)
+	v, ok2 := value.(string)
+	return ok1 && ok2 && v != "" && slices.Contains(values, v)
+}
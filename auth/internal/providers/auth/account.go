@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CheckActive fails with ErrAccountDisabled for deactivated, deleted and
+// erased accounts. Tokens must not be issued for them.
+func (p AuthProvider) CheckActive(ctx context.Context, userID string) error {
+	active, err := p.repository.IsUserActive(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !active {
+		return ErrAccountDisabled
+	}
+	return nil
+}
+
+// DeactivateUser blocks login and revokes all sessions until an admin
+// reactivates the account.
+func (p AuthProvider) DeactivateUser(ctx context.Context, userID string) error {
+	ok, err := p.repository.DeactivateUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteUser soft-deletes the account. It can still be restored until the
+// erasure job anonymizes it after the retention period.
+func (p AuthProvider) DeleteUser(ctx context.Context, userID string) error {
+	ok, err := p.repository.SoftDeleteUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteOwnAccount is the self-service variant of DeleteUser, confirmed with
+// the current password. The hash is loaded by ID, accounts left without a
+// canonical username by identity conflicts can delete themselves too.
+func (p AuthProvider) DeleteOwnAccount(ctx context.Context, userID, password string) error {
+	passwordHash, err := p.repository.GetPasswordHash(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) != nil {
+		return ErrInvalidCredentials
+	}
+
+	return p.DeleteUser(ctx, userID)
+}
+
+// ReactivateUser lifts a deactivation or a soft delete. Erased accounts
+// cannot be restored.
+func (p AuthProvider) ReactivateUser(ctx context.Context, userID string) error {
+	ok, err := p.repository.ReactivateUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reactivate user: %w", err)
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	return nil
+}
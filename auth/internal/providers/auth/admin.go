@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minAdminPasswordLength applies to passwords set by operators, see
+// CreateAdmin and ResetPassword.
+const minAdminPasswordLength = 12
+
+// CreateAdmin creates an account holding the admin role globally. Unlike
+// RegisterUser it ignores the registration mode, it is meant to bootstrap
+// the first admin.
+func (p AuthProvider) CreateAdmin(ctx context.Context, user RegisterUserData) (string, error) {
+	var err error
+	if user.Username, err = p.validateUsername(user.Username); err != nil {
+		return "", err
+	}
+	if user.Email, err = p.validateEmail(user.Email); err != nil {
+		return "", err
+	}
+	if err := p.checkIdentityAvailable(ctx, user.Username, user.Email); err != nil {
+		return "", err
+	}
+
+	hash, err := hashAdminPassword(user.Password)
+	if err != nil {
+		return "", err
+	}
+	user.Password = hash
+
+	userID, err := p.repository.CreateAdmin(ctx, ProviderRegisterReq2DB(user))
+	if err != nil {
+		return "", fmt.Errorf("failed to create admin: %w", err)
+	}
+	return userID, nil
+}
+
+// ResetPassword replaces the user's password and signs them out everywhere.
+func (p AuthProvider) ResetPassword(ctx context.Context, userID, password string) error {
+	hash, err := hashAdminPassword(password)
+	if err != nil {
+		return err
+	}
+
+	updated, err := p.repository.ResetPassword(ctx, userID, hash)
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	if !updated {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// GetRoleMembers lists the users holding the role globally.
+func (p AuthProvider) GetRoleMembers(ctx context.Context, role string) ([]RoleMember, error) {
+	if exists, err := p.repository.RoleExists(ctx, role); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, ErrRoleNotFound
+	}
+
+	members, err := p.repository.GetRoleMembers(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]RoleMember, 0, len(members))
+	for _, member := range members {
+		result = append(result, DBRoleMember2Provider(member))
+	}
+	return result, nil
+}
+
+// FindUser resolves a user ID, email or username to the user's ID.
+func (p AuthProvider) FindUser(ctx context.Context, login string) (string, error) {
+	if uuidPattern.MatchString(login) {
+		exists, err := p.repository.FindUserByID(ctx, login)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", ErrUserNotFound
+		}
+		return login, nil
+	}
+
+	var userID string
+	var err error
+	if strings.Contains(login, "@") {
+		userID, _, err = p.repository.FindUserByEmail(ctx, login)
+	} else {
+		userID, _, err = p.repository.FindUserByUsername(ctx, login)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	return userID, nil
+}
+
+func hashAdminPassword(password string) (string, error) {
+	if len(password) < minAdminPasswordLength {
+		return "", fmt.Errorf("%w: at least %d characters are required", ErrInvalidPassword, minAdminPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", ErrHashingPassword
+	}
+	return string(hash), nil
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+const (
+	AuditAccountDeactivated  = "account.deactivated"
+	AuditAccountDeleted      = "account.deleted"
+	AuditAccountReactivated  = "account.reactivated"
+	AuditEmailChanged        = "email.changed"
+	AuditUsernameChanged     = "username.changed"
+	AuditConflictResolved    = "identity_conflict.resolved"
+	AuditRoleActivated       = "role.activated"
+	AuditDataExported        = "data.exported"
+	AuditRoleCreated         = "role.created"
+	AuditRoleDeleted         = "role.deleted"
+	AuditPermissionGranted   = "permission.granted"
+	AuditPermissionRevoked   = "permission.revoked"
+	AuditRoleGranted         = "role.granted"
+	AuditRoleRevoked         = "role.revoked"
+	AuditRoleExpired         = "role.expired"
+	AuditGroupAssigned       = "teacher_group.assigned"
+	AuditGroupUnassigned     = "teacher_group.unassigned"
+	AuditUserImpersonated    = "user.impersonated"
+	AuditPasswordReset       = "password.reset"
+	AuditKeyIssued           = "key.issued"
+	AuditRoleRequested       = "role_request.created"
+	AuditRoleRequestApproved = "role_request.approved"
+	AuditRoleRequestRejected = "role_request.rejected"
+)
+
+// AuditEvent records who (ActorID) did what (Action) to whom (SubjectID).
+type AuditEvent struct {
+	ActorID   string
+	SubjectID string
+	Action    string
+	Details   map[string]any
+	IP        string
+}
+
+// RecordAuditEvent never fails the operation being audited, a lost event is
+// only logged.
+func (p AuthProvider) RecordAuditEvent(ctx context.Context, event AuditEvent) {
+	if err := p.CreateAuditEvent(ctx, event); err != nil {
+		log.Default().Printf("[ERR] record audit event %s: %s\n", event.Action, err.Error())
+	}
+}
+
+// CreateAuditEvent is RecordAuditEvent for operations that must not happen
+// without a trace, the caller stops when it fails.
+func (p AuthProvider) CreateAuditEvent(ctx context.Context, event AuditEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil || event.Details == nil {
+		details = []byte("{}")
+	}
+
+	return p.repository.CreateAuditEvent(ctx, models.AuditEvent{
+		ActorID:   sql.NullString{String: event.ActorID, Valid: event.ActorID != ""},
+		SubjectID: sql.NullString{String: event.SubjectID, Valid: event.SubjectID != ""},
+		Action:    event.Action,
+		Details:   details,
+		IP:        sql.NullString{String: event.IP, Valid: event.IP != ""},
+	})
+}
+
+// keyRedemption keeps the attributes of a redeemed activation key. The key
+// itself is not stored, only its jti if it has one. UserID is left empty.
+func keyRedemption(role string, claims jwt.MapClaims) models.KeyRedemption {
+	attributes := make(map[string]any, len(claims))
+	for name, value := range claims {
+		if name != "jti" && name != "role" && name != "exp" {
+			attributes[name] = value
+		}
+	}
+	attributesJSON, err := json.Marshal(attributes)
+	if err != nil {
+		attributesJSON = []byte("{}")
+	}
+
+	keyID, _ := claims["jti"].(string)
+	return models.KeyRedemption{
+		Role:       role,
+		KeyID:      sql.NullString{String: keyID, Valid: keyID != ""},
+		Attributes: attributesJSON,
+	}
+}
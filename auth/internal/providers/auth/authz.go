@@ -0,0 +1,391 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/policy"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+// Resource types understood by CheckAccess, written as "<type>:<id>". An empty
+// resource stands for the service as a whole.
+const (
+	ResourceUniversity = "university"
+	ResourceGroup      = "group"
+	ResourceUser       = "user"
+)
+
+// ownerPermissions are granted on a user's own record whatever their roles.
+var ownerPermissions = []string{PermUsersRead, PermUsersReadEmail, PermStudentsRead, PermTeachersRead, PermRolesRead}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type AccessCheck struct {
+	Action   string
+	Resource string
+}
+
+type AccessDecision struct {
+	Action   string
+	Resource string
+	Allowed  bool
+	Reason   string
+}
+
+// AccessExplanation is a decision together with what it was based on: the
+// outcome of the role assignments alone, the attributes policies saw and
+// what every policy concluded.
+type AccessExplanation struct {
+	AccessDecision
+	RoleAllowed bool
+	RoleReason  string
+	Subject     policy.Attributes
+	Resource    policy.Attributes
+	Policies    []policy.Trace
+
+	// err is why the resource could not be resolved, if it could not.
+	err error
+}
+
+// subject is everything needed to decide on the checks of one user, loaded
+// once per batch.
+type subject struct {
+	id          string
+	permissions EffectivePermissions
+	// teaches and teacherPermissions are only set for holders of the teacher
+	// role: teaching a group grants the teacher role's permissions there.
+	teaches            []string
+	teacherPermissions []string
+	attributes         policy.Attributes
+}
+
+type resolvedResource struct {
+	Resource
+	attributes policy.Attributes
+}
+
+// CheckAccess decides each check for the user. Decisions are based on the
+// user's role assignments and their scopes, the groups they teach, ownership
+// of user records and the configured policies. Inactive users are denied
+// everything.
+func (p AuthProvider) CheckAccess(ctx context.Context, userID string, checks []AccessCheck) ([]AccessDecision, error) {
+	explanations, err := p.ExplainAccess(ctx, userID, checks, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions := make([]AccessDecision, 0, len(explanations))
+	for _, explanation := range explanations {
+		decisions = append(decisions, explanation.AccessDecision)
+	}
+	return decisions, nil
+}
+
+// Authorize decides a single check. Unlike CheckAccess it fails with
+// ErrResourceNotFound, ErrInvalidResource or ErrGroupUniversityConflict when
+// the resource can not be resolved, so that callers can tell a missing resource from a denial.
+func (p AuthProvider) Authorize(ctx context.Context, userID, action, resource string) (AccessDecision, error) {
+	explanations, err := p.ExplainAccess(ctx, userID, []AccessCheck{{Action: action, Resource: resource}}, nil)
+	if err != nil {
+		return AccessDecision{}, err
+	}
+	if explanations[0].err != nil {
+		return AccessDecision{}, explanations[0].err
+	}
+	return explanations[0].AccessDecision, nil
+}
+
+// ExplainAccess is CheckAccess with the reasoning behind every decision.
+// Passing policies evaluates those instead of the loaded ones, so that a
+// policy file can be tried out before it is deployed.
+func (p AuthProvider) ExplainAccess(ctx context.Context, userID string, checks []AccessCheck, policies *policy.Set) ([]AccessExplanation, error) {
+	explanations := make([]AccessExplanation, len(checks))
+	for i, check := range checks {
+		explanations[i].AccessDecision = AccessDecision{Action: check.Action, Resource: check.Resource}
+	}
+
+	// The ID comes from other services as is, the database would reject a
+	// malformed one with an error instead of finding nothing.
+	if !uuidPattern.MatchString(userID) {
+		return denyAll(explanations, "subject not found"), nil
+	}
+	if policies == nil {
+		policies = p.policies.Policies()
+	}
+
+	if err := p.CheckActive(ctx, userID); err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			return denyAll(explanations, "subject not found"), nil
+		case errors.Is(err, ErrAccountDisabled):
+			return denyAll(explanations, "subject account is deactivated"), nil
+		default:
+			return nil, err
+		}
+	}
+
+	sub, err := p.loadSubject(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := p.repository.GetPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make(map[string]resolvedResource)
+	for i, check := range checks {
+		explanation := &explanations[i]
+		explanation.Subject = sub.attributes
+
+		if !slices.ContainsFunc(known, func(permission models.Permission) bool { return permission.Name == check.Action }) {
+			explanation.Reason = "unknown action"
+			continue
+		}
+
+		resource, ok := resources[check.Resource]
+		if !ok {
+			resource, err = p.resolveResource(ctx, check.Resource)
+			switch {
+			case errors.Is(err, ErrInvalidResource), errors.Is(err, ErrResourceNotFound),
+				errors.Is(err, ErrGroupUniversityConflict):
+				explanation.Reason, explanation.err = err.Error(), err
+				continue
+			case err != nil:
+				return nil, err
+			}
+			resources[check.Resource] = resource
+		}
+		explanation.Resource = resource.attributes
+
+		explanation.RoleAllowed, explanation.RoleReason = sub.decide(check.Action, resource.Resource)
+		evaluation := policies.Evaluate(policy.Input{
+			Action:   check.Action,
+			Subject:  sub.attributes,
+			Resource: resource.attributes,
+		})
+		explanation.Policies = evaluation.Trace
+
+		switch {
+		case evaluation.Effect == policy.EffectDeny:
+			explanation.Reason = fmt.Sprintf("denied by policy %s", evaluation.Policy)
+		case explanation.RoleAllowed:
+			explanation.Allowed, explanation.Reason = true, explanation.RoleReason
+		case evaluation.Effect == policy.EffectAllow:
+			explanation.Allowed, explanation.Reason = true, fmt.Sprintf("allowed by policy %s", evaluation.Policy)
+		default:
+			explanation.Reason = explanation.RoleReason
+		}
+	}
+
+	return explanations, nil
+}
+
+func (p AuthProvider) loadSubject(ctx context.Context, userID string) (subject, error) {
+	sub := subject{id: userID}
+
+	permissions, err := p.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return subject{}, err
+	}
+	sub.permissions = permissions
+
+	roles, err := p.repository.GetUserRoles(ctx, userID)
+	if err != nil {
+		return subject{}, err
+	}
+
+	if slices.Contains(roles, "teacher") {
+		if sub.teaches, err = p.repository.GetTeacherGroups(ctx, userID); err != nil {
+			return subject{}, err
+		}
+		if sub.teacherPermissions, err = p.repository.GetRolePermissions(ctx, "teacher"); err != nil {
+			return subject{}, err
+		}
+	}
+
+	affiliation, err := p.repository.GetUserAffiliation(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return subject{}, err
+	}
+
+	sub.attributes = policy.Attributes{
+		"id":            userID,
+		"roles":         nonNil(roles),
+		"university_id": affiliation.UniversityID,
+		"group_id":      affiliation.GroupID,
+		"groups":        nonNil(sub.teaches),
+	}
+	return sub, nil
+}
+
+// resolveResource turns "<type>:<id>" into the university and group the
+// resource belongs to. Users belong to the university and group of their
+// profile, groups to the university of their students. A group without
+// students has no university, one whose students disagree fails with
+// ErrGroupUniversityConflict.
+func (p AuthProvider) resolveResource(ctx context.Context, resource string) (resolvedResource, error) {
+	if resource == "" {
+		return resolvedResource{attributes: policy.Attributes{}}, nil
+	}
+
+	kind, id, ok := strings.Cut(resource, ":")
+	if !ok || !uuidPattern.MatchString(id) {
+		return resolvedResource{}, ErrInvalidResource
+	}
+	// Scope IDs come from the database in lowercase.
+	id = strings.ToLower(id)
+
+	attributes := policy.Attributes{"type": kind, "id": id}
+	switch kind {
+	case ResourceUniversity:
+		attributes["university_id"] = id
+		return resolvedResource{Resource{UniversityID: id}, attributes}, nil
+	case ResourceGroup:
+		universities, err := p.repository.GetGroupUniversities(ctx, id)
+		if err != nil {
+			return resolvedResource{}, err
+		}
+		if len(universities) > 1 {
+			return resolvedResource{}, ErrGroupUniversityConflict
+		}
+		var universityID string
+		if len(universities) == 1 {
+			universityID = universities[0]
+		}
+
+		attributes["group_id"] = id
+		attributes["university_id"] = universityID
+		return resolvedResource{Resource{UniversityID: universityID, GroupID: id}, attributes}, nil
+	case ResourceUser:
+		affiliation, err := p.repository.GetUserAffiliation(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return resolvedResource{}, ErrResourceNotFound
+			}
+			return resolvedResource{}, err
+		}
+		roles, err := p.repository.GetUserRoles(ctx, id)
+		if err != nil {
+			return resolvedResource{}, err
+		}
+
+		attributes["university_id"] = affiliation.UniversityID
+		attributes["group_id"] = affiliation.GroupID
+		attributes["roles"] = nonNil(roles)
+		return resolvedResource{
+			Resource:   Resource{UniversityID: affiliation.UniversityID, GroupID: affiliation.GroupID, OwnerID: id},
+			attributes: attributes,
+		}, nil
+	default:
+		return resolvedResource{}, ErrInvalidResource
+	}
+}
+
+// decide returns whether the subject's roles allow the action on the
+// resource, and why.
+func (s subject) decide(action string, resource Resource) (bool, string) {
+	if slices.Contains(s.permissions.Global, action) {
+		return true, "granted by a global role assignment"
+	}
+
+	for _, permission := range s.permissions.Scoped {
+		if permission.Permission != action {
+			continue
+		}
+		switch {
+		case permission.Scope.Type == ScopeUniversity && permission.Scope.ID == resource.UniversityID:
+			return true, fmt.Sprintf("granted by a role assignment in university %s", resource.UniversityID)
+		case permission.Scope.Type == ScopeGroup && permission.Scope.ID == resource.GroupID:
+			return true, fmt.Sprintf("granted by a role assignment in group %s", resource.GroupID)
+		}
+	}
+
+	if resource.GroupID != "" && slices.Contains(s.teaches, resource.GroupID) && slices.Contains(s.teacherPermissions, action) {
+		return true, fmt.Sprintf("subject teaches group %s", resource.GroupID)
+	}
+
+	if resource.OwnerID == s.id && slices.Contains(ownerPermissions, action) {
+		return true, "subject owns the resource"
+	}
+
+	return false, "no role assignment grants the action on the resource"
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func denyAll(explanations []AccessExplanation, reason string) []AccessExplanation {
+	for i := range explanations {
+		explanations[i].Reason = reason
+	}
+	return explanations
+}
+
+// Teacher groups...
+
+func (p AuthProvider) GetTeacherGroups(ctx context.Context, userID string) ([]string, error) {
+	exists, err := p.repository.FindUserByID(ctx, userID)
+	if err != nil || !exists {
+		return nil, ErrUserNotFound
+	}
+
+	groups, err := p.repository.GetTeacherGroups(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if groups == nil {
+		groups = []string{}
+	}
+	return groups, nil
+}
+
+// AssignTeacherGroup records that the teacher teaches the group. Assigning a
+// group twice is not an error.
+func (p AuthProvider) AssignTeacherGroup(ctx context.Context, userID, groupID string) error {
+	if !uuidPattern.MatchString(groupID) {
+		return ErrInvalidResource
+	}
+
+	exists, err := p.repository.FindUserByID(ctx, userID)
+	if err != nil || !exists {
+		return ErrUserNotFound
+	}
+
+	if teacher, err := p.repository.CheckUserRole(ctx, userID, "teacher"); err != nil {
+		return err
+	} else if !teacher {
+		return ErrNotTeacher
+	}
+
+	if _, err := p.repository.AddTeacherGroup(ctx, userID, groupID); err != nil {
+		return fmt.Errorf("failed to assign group: %w", err)
+	}
+	return nil
+}
+
+func (p AuthProvider) UnassignTeacherGroup(ctx context.Context, userID, groupID string) error {
+	if !uuidPattern.MatchString(groupID) {
+		return ErrInvalidResource
+	}
+
+	removed, err := p.repository.RemoveTeacherGroup(ctx, userID, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to unassign group: %w", err)
+	}
+	if !removed {
+		return ErrResourceNotFound
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+// TestCheckAccessDeniesMalformedSubject runs on an empty provider, a subject
+// ID that reached the repository would panic instead of being denied.
+func TestCheckAccessDeniesMalformedSubject(t *testing.T) {
+	checks := []AccessCheck{{Action: "students:read:group", Resource: "group:3fa85f64-5717-4562-b3fc-2c963f66afa6"}}
+
+	for _, userID := range []string{"not-a-uuid", "1; DROP TABLE users", ""} {
+		decisions, err := AuthProvider{}.CheckAccess(context.Background(), userID, checks)
+		if err != nil {
+			t.Fatalf("CheckAccess(%q): %v", userID, err)
+		}
+		if len(decisions) != 1 || decisions[0].Allowed || decisions[0].Reason != "subject not found" {
+			t.Fatalf("CheckAccess(%q): got %+v, want a denial for a missing subject", userID, decisions)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/clients/mailer"
+)
+
+const defaultEmailChangeTTL = 24 * time.Hour
+
+// RequestEmailChange starts an email change. The new address gets a
+// confirmation link and the current one a notice with a cancel link. The
+// email is only swapped once the confirmation link is used.
+func (p AuthProvider) RequestEmailChange(ctx context.Context, userID, newEmail string) error {
+	newEmail, err := p.validateEmail(newEmail)
+	if err != nil {
+		return err
+	}
+
+	user, err := p.repository.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return fmt.Errorf("%w: new email matches the current one", ErrInvalidEmail)
+	}
+
+	if _, _, err := p.repository.FindUserByEmail(ctx, newEmail); err == nil {
+		return ErrEmailExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// Only the latest request can be confirmed.
+	if err := p.repository.CancelPendingEmailChanges(ctx, userID); err != nil {
+		return fmt.Errorf("failed to cancel pending email changes: %w", err)
+	}
+
+	confirmToken, err := newURLToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate confirm token: %w", err)
+	}
+	cancelToken, err := newURLToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate cancel token: %w", err)
+	}
+
+	ttl := p.emailChangeTTL()
+	if err := p.repository.CreateEmailChangeRequest(ctx, userID, user.Email, newEmail,
+		hashToken(confirmToken), hashToken(cancelToken), time.Now().Add(ttl)); err != nil {
+		return fmt.Errorf("failed to store email change request: %w", err)
+	}
+
+	if err := p.mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Follow the link to use this address for your account:\n\n%s\n\nThe link expires in %s.",
+			withToken(p.conf.Security.EmailChange.ConfirmURL, confirmToken), ttl),
+	}); err != nil {
+		return fmt.Errorf("failed to send confirmation: %w", err)
+	}
+
+	if err := p.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("A change of your account email to %s was requested.\n\n"+
+			"If this was not you, cancel it here:\n\n%s",
+			newEmail, withToken(p.conf.Security.EmailChange.CancelURL, cancelToken)),
+	}); err != nil {
+		return fmt.Errorf("failed to send notice: %w", err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange swaps the email and revokes all sessions of the user.
+// It returns the ID of the user whose email changed.
+func (p AuthProvider) ConfirmEmailChange(ctx context.Context, token string) (string, error) {
+	change, swapped, err := p.repository.ConfirmEmailChange(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrInvalidEmailChange
+		}
+		return "", err
+	}
+	if !swapped {
+		return "", ErrEmailExists
+	}
+
+	return change.UserID, nil
+}
+
+func (p AuthProvider) CancelEmailChange(ctx context.Context, token string) error {
+	if _, err := p.repository.CancelEmailChange(ctx, hashToken(token)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidEmailChange
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (p AuthProvider) validateEmail(email string) (string, error) {
+	email, err := p.validator.Email(email)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidEmail, err)
+	}
+	return email, nil
+}
+
+func (p AuthProvider) emailChangeTTL() time.Duration {
+	if ttl := p.conf.Security.EmailChange.TTL; ttl > 0 {
+		return ttl
+	}
+	return defaultEmailChangeTTL
+}
+
+func withToken(base, token string) string {
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
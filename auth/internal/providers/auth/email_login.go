@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/clients/mailer"
+)
+
+const (
+	EmailLoginLink = "link"
+	EmailLoginCode = "code"
+
+	emailLoginCodeDigits      = 6
+	emailLoginCodeMaxAttempts = 5
+	emailLoginLinkBytes       = 32
+	defaultEmailLoginTTL      = 15 * time.Minute
+	// emailLoginMinDuration covers the database work done only for
+	// registered addresses, the mail itself is sent in the background.
+	emailLoginMinDuration = 500 * time.Millisecond
+)
+
+// RequestEmailLogin sends a single-use login link or code to the address.
+// Unknown and locked accounts are skipped silently, delivery happens after
+// the call returns and every call takes at least emailLoginMinDuration, so
+// the caller cannot tell whether the email is registered.
+func (p AuthProvider) RequestEmailLogin(ctx context.Context, email, method string) error {
+	if method != EmailLoginLink && method != EmailLoginCode {
+		return ErrInvalidLoginMethod
+	}
+
+	deadline := time.Now().Add(emailLoginMinDuration)
+	defer func() { time.Sleep(time.Until(deadline)) }()
+
+	userID, _, err := p.repository.FindUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if err := p.CheckActive(ctx, userID); err != nil {
+		if errors.Is(err, ErrAccountDisabled) {
+			log.Default().Println("[EMAIL LOGIN] skipped for inactive account:", userID)
+			return nil
+		}
+		return err
+	}
+
+	if err := p.checkLockout(ctx, userID); err != nil {
+		if errors.Is(err, ErrAccountLocked) {
+			log.Default().Println("[EMAIL LOGIN] skipped for locked account:", userID)
+			return nil
+		}
+		return err
+	}
+
+	// Only the latest link or code is valid.
+	if err := p.repository.RevokeEmailLoginTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke email login tokens: %w", err)
+	}
+
+	var secret, secretHash string
+	var msg mailer.Message
+	switch method {
+	case EmailLoginLink:
+		secret, err = newURLToken()
+		secretHash = hashEmailLoginSecret("", secret)
+		msg = mailer.Message{
+			To:      email,
+			Subject: "Your sign-in link",
+			Body: fmt.Sprintf("Follow the link to sign in:\n\n%s\n\nThe link expires in %s and works once.",
+				p.emailLoginURL(secret), p.emailLoginTTL()),
+		}
+	case EmailLoginCode:
+		secret, err = newEmailLoginCode()
+		secretHash = hashEmailLoginSecret(userID, secret)
+		msg = mailer.Message{
+			To:      email,
+			Subject: "Your sign-in code",
+			Body: fmt.Sprintf("Your sign-in code is %s\n\nThe code expires in %s and works once.",
+				secret, p.emailLoginTTL()),
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate email login secret: %w", err)
+	}
+
+	expiresAt := time.Now().Add(p.emailLoginTTL())
+	if err := p.repository.CreateEmailLoginToken(ctx, userID, method, secretHash, expiresAt); err != nil {
+		return fmt.Errorf("failed to store email login token: %w", err)
+	}
+
+	// Waiting for the mail server, or returning its failure, would tell
+	// registered addresses apart from unknown ones.
+	go func() {
+		if err := p.mailer.Send(context.WithoutCancel(ctx), msg); err != nil {
+			log.Default().Println("[ERR] send email login:", err.Error())
+		}
+	}()
+
+	return nil
+}
+
+func (p AuthProvider) VerifyEmailLink(ctx context.Context, token string, client ClientInfo) (string, error) {
+	userID, err := p.repository.ConsumeEmailLoginLink(ctx, hashEmailLoginSecret("", token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrInvalidEmailLogin
+		}
+		return "", err
+	}
+
+	if err := p.checkLockout(ctx, userID); err != nil {
+		return "", err
+	}
+
+	p.recordLoginAttempt(ctx, userID, "", LoginMethodEmailLink, true, client)
+	return userID, nil
+}
+
+func (p AuthProvider) VerifyEmailCode(ctx context.Context, email, code string, client ClientInfo) (string, error) {
+	userID, _, err := p.repository.FindUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			p.recordLoginAttempt(ctx, "", email, LoginMethodEmailCode, false, client)
+			return "", ErrInvalidEmailLogin
+		}
+		return "", err
+	}
+
+	if err := p.checkLockout(ctx, userID); err != nil {
+		return "", err
+	}
+
+	consumed, err := p.repository.ConsumeEmailLoginCode(ctx, userID, hashEmailLoginSecret(userID, code), emailLoginCodeMaxAttempts)
+	if err != nil {
+		return "", err
+	}
+	if !consumed {
+		if err := p.repository.IncrementEmailLoginAttempts(ctx, userID); err != nil {
+			return "", err
+		}
+		p.recordLoginAttempt(ctx, userID, email, LoginMethodEmailCode, false, client)
+		return "", ErrInvalidEmailLogin
+	}
+
+	p.recordLoginAttempt(ctx, userID, email, LoginMethodEmailCode, true, client)
+	return userID, nil
+}
+
+func (p AuthProvider) emailLoginTTL() time.Duration {
+	if ttl := p.conf.Security.EmailLogin.TTL; ttl > 0 {
+		return ttl
+	}
+	return defaultEmailLoginTTL
+}
+
+func (p AuthProvider) emailLoginURL(token string) string {
+	return withToken(p.conf.Security.EmailLogin.LinkURL, token)
+}
+
+func newURLToken() (string, error) {
+	buf := make([]byte, emailLoginLinkBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func newEmailLoginCode() (string, error) {
+	max := big.NewInt(1_000_000)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", emailLoginCodeDigits, n.Int64()), nil
+}
+
+// hashEmailLoginSecret salts codes with the user ID, since six digits alone
+// would collide between users.
+func hashEmailLoginSecret(userID, secret string) string {
+	sum := sha256.Sum256([]byte(userID + ":" + secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,10 +3,55 @@ package auth
 import "errors"
 
 var (
-	ErrUsernameExists    = errors.New("username already exists")
-	ErrEmailExists       = errors.New("email already exists")
-	ErrIncorrectPassword = errors.New("incorrect password")
-	ErrInvalidRole       = errors.New("invalid role")
-	ErrUserNotFound      = errors.New("user not found")
-	ErrHashingPassword   = errors.New("password hashing error")
+	ErrUsernameExists     = errors.New("username already exists")
+	ErrEmailExists        = errors.New("email already exists")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrHashingPassword    = errors.New("password hashing error")
+	ErrAccountLocked      = errors.New("account temporarily locked")
+	ErrInvalidEmailLogin  = errors.New("invalid or expired email login")
+	ErrInvalidLoginMethod = errors.New("invalid email login method")
+	ErrInvalidProfile     = errors.New("invalid profile")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrInvalidEmailChange = errors.New("invalid or expired email change")
+	ErrInvalidUsername    = errors.New("invalid username")
+	ErrUsernameReserved   = errors.New("username is reserved")
+	ErrAccountDisabled    = errors.New("account is deactivated or deleted")
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrConflictNotFound   = errors.New("identity conflict not found or already resolved")
+
+	ErrRegistrationClosed    = errors.New("registration is closed")
+	ErrActivationKeyRequired = errors.New("registration requires an activation key")
+	ErrEmailDomainNotAllowed = errors.New("email domain is not allowed to register")
+	ErrInvalidActivationKey  = errors.New("invalid activation key")
+	ErrActivationKeyRedeemed = errors.New("activation key was already redeemed")
+
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleExists         = errors.New("role already exists")
+	ErrBuiltinRole        = errors.New("built-in role can not be changed this way")
+	ErrPermissionNotFound = errors.New("permission not found")
+
+	ErrInvalidScope           = errors.New("invalid role scope")
+	ErrRoleAlreadyGranted     = errors.New("role already granted in this scope")
+	ErrRoleRequiresActivation = errors.New("role must first be activated with an activation key or role request")
+	ErrRoleNotGranted         = errors.New("user does not have this role")
+	ErrInvalidExpiry          = errors.New("expires_at must be in the future")
+
+	ErrInvalidResource         = errors.New("invalid resource")
+	ErrResourceNotFound        = errors.New("resource not found")
+	ErrGroupUniversityConflict = errors.New("students of the group belong to different universities")
+	ErrNotTeacher              = errors.New("user is not a teacher")
+
+	ErrInvalidRoleRequest   = errors.New("invalid role request")
+	ErrRoleRequestNotFound  = errors.New("role request not found")
+	ErrRoleRequestPending   = errors.New("a request for this role is already pending")
+	ErrRoleRequestReviewed  = errors.New("role request was already reviewed")
+	ErrRoleAlreadyActivated = errors.New("role already activated")
+	ErrSelfReview           = errors.New("can not review your own role request")
+
+	ErrInvalidKeyRequest = errors.New("invalid key request")
+	ErrKeyNotDelegable   = errors.New("teachers may only issue student keys")
+	ErrKeyOutOfScope     = errors.New("key is outside the groups or university of the teacher")
+	ErrKeyQuotaExceeded  = errors.New("activation key quota exceeded")
 )
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ExportUserData collects everything stored about the user across the
+// repository tables. Secrets such as the password hash and token hashes are
+// left out.
+func (p AuthProvider) ExportUserData(ctx context.Context, userID string) (DataExport, error) {
+	user, err := p.repository.GetUserRecord(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DataExport{}, ErrUserNotFound
+		}
+		return DataExport{}, err
+	}
+
+	profile, err := p.GetProfile(ctx, userID)
+	if err != nil {
+		return DataExport{}, err
+	}
+
+	export := DataExport{
+		User:    DBUserRecord2Provider(user),
+		Roles:   profile.Roles,
+		Student: profile.Student,
+		Teacher: profile.Teacher,
+	}
+
+	attempts, err := p.repository.GetLoginAttempts(ctx, userID)
+	if err != nil {
+		return DataExport{}, fmt.Errorf("failed to get login history: %w", err)
+	}
+	for _, attempt := range attempts {
+		export.LoginHistory = append(export.LoginHistory, DBLoginAttempt2Provider(attempt))
+	}
+
+	usernames, err := p.repository.GetUsernameHistory(ctx, userID)
+	if err != nil {
+		return DataExport{}, fmt.Errorf("failed to get username history: %w", err)
+	}
+	for _, entry := range usernames {
+		export.UsernameHistory = append(export.UsernameHistory, DBUsernameHistory2Provider(entry))
+	}
+
+	emailChanges, err := p.repository.GetEmailChangeRequests(ctx, userID)
+	if err != nil {
+		return DataExport{}, fmt.Errorf("failed to get email changes: %w", err)
+	}
+	for _, request := range emailChanges {
+		export.EmailChanges = append(export.EmailChanges, DBEmailChangeRequest2Provider(request))
+	}
+
+	redemptions, err := p.repository.GetKeyRedemptions(ctx, userID)
+	if err != nil {
+		return DataExport{}, fmt.Errorf("failed to get activation keys: %w", err)
+	}
+	for _, redemption := range redemptions {
+		export.ActivationKeys = append(export.ActivationKeys, DBKeyRedemption2Provider(redemption))
+	}
+
+	issuances, err := p.repository.GetKeyIssuances(ctx, userID)
+	if err != nil {
+		return DataExport{}, fmt.Errorf("failed to get issued keys: %w", err)
+	}
+	for _, issuance := range issuances {
+		export.IssuedKeys = append(export.IssuedKeys, DBKeyIssuance2Provider(issuance))
+	}
+
+	if export.RoleRequests, err = p.GetUserRoleRequests(ctx, userID); err != nil {
+		return DataExport{}, fmt.Errorf("failed to get role requests: %w", err)
+	}
+
+	events, err := p.repository.GetAuditEventsByUser(ctx, userID)
+	if err != nil {
+		return DataExport{}, fmt.Errorf("failed to get audit events: %w", err)
+	}
+	for _, event := range events {
+		export.AuditEvents = append(export.AuditEvents, DBAuditEvent2Provider(event))
+	}
+
+	return export, nil
+}
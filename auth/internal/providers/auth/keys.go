@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+// ValidateKeyRequest checks that the key carries everything its role needs.
+func ValidateKeyRequest(request KeyRequest) error {
+	switch request.Role {
+	case "student":
+		if request.GroupID == "" || request.UniversityID == "" || request.EnrollmentYear == 0 {
+			return fmt.Errorf("%w: group_id, university_id and enrollment_year are required for student", ErrInvalidKeyRequest)
+		}
+	case "teacher":
+		if request.UniversityID == "" || request.Degree == "" {
+			return fmt.Errorf("%w: university_id and degree are required for teacher", ErrInvalidKeyRequest)
+		}
+	default:
+		return fmt.Errorf("%w: invalid role", ErrInvalidKeyRequest)
+	}
+	return nil
+}
+
+// CheckDelegatedKey decides whether the teacher may issue the key: student
+// keys only, for a group the teacher teaches, in the university of their
+// teacher profile. An empty university defaults to the teacher's. The quota
+// is enforced when the issuance is recorded, see RecordKeyIssuance.
+func (p AuthProvider) CheckDelegatedKey(ctx context.Context, teacherID string, request *KeyRequest) error {
+	if request.Role != "student" {
+		return ErrKeyNotDelegable
+	}
+
+	if activated, err := p.repository.CheckUserRole(ctx, teacherID, "teacher"); err != nil {
+		return fmt.Errorf("failed to check role activation: %w", err)
+	} else if !activated {
+		return ErrNotTeacher
+	}
+
+	teacher, err := p.repository.GetTeacherByID(ctx, teacherID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotTeacher
+		}
+		return err
+	}
+	if request.UniversityID == "" {
+		request.UniversityID = teacher.UniversityID
+	}
+	if err := ValidateKeyRequest(*request); err != nil {
+		return err
+	}
+	if !strings.EqualFold(request.UniversityID, teacher.UniversityID) {
+		return ErrKeyOutOfScope
+	}
+
+	groups, err := p.repository.GetTeacherGroups(ctx, teacherID)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(groups, func(groupID string) bool { return strings.EqualFold(groupID, request.GroupID) }) {
+		return ErrKeyOutOfScope
+	}
+
+	return nil
+}
+
+// RecordKeyIssuance keeps the attributes of an issued key, quotas are counted
+// from these rows. Delegated keys, issued by teachers, count against the
+// configured teacher quota and fail with ErrKeyQuotaExceeded once it is used
+// up; the key must not be handed out then.
+func (p AuthProvider) RecordKeyIssuance(ctx context.Context, issuedBy, keyID string, request KeyRequest, delegated bool) error {
+	attributes := map[string]any{"university_id": request.UniversityID}
+	switch request.Role {
+	case "student":
+		attributes["group_id"] = request.GroupID
+		attributes["enrollment_year"] = request.EnrollmentYear
+	case "teacher":
+		attributes["degree"] = request.Degree
+	}
+	attributesJSON, err := json.Marshal(attributes)
+	if err != nil {
+		return err
+	}
+
+	issuance := models.KeyIssuance{
+		KeyID:      keyID,
+		IssuedBy:   sql.NullString{String: issuedBy, Valid: issuedBy != ""},
+		Role:       request.Role,
+		Attributes: attributesJSON,
+	}
+
+	quota := p.conf.Security.Keys.TeacherQuota
+	if !delegated || quota.Limit <= 0 {
+		if err := p.repository.CreateKeyIssuance(ctx, issuance); err != nil {
+			return fmt.Errorf("failed to record key issuance: %w", err)
+		}
+		return nil
+	}
+
+	recorded, err := p.repository.CreateLimitedKeyIssuance(ctx, issuance, quota.Limit, time.Now().Add(-quota.Window))
+	if err != nil {
+		return fmt.Errorf("failed to record key issuance: %w", err)
+	}
+	if !recorded {
+		return ErrKeyQuotaExceeded
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+// checkLockout refuses logins for an account that collected too many failed
+// attempts within the configured window since its last successful login.
+func (p AuthProvider) checkLockout(ctx context.Context, userID string) error {
+	lockout := p.conf.Security.Lockout
+	if lockout.MaxFailures <= 0 {
+		return nil
+	}
+
+	failures, err := p.repository.CountLoginFailures(ctx, userID, time.Now().Add(-lockout.Window))
+	if err != nil {
+		return fmt.Errorf("failed to count login failures: %w", err)
+	}
+	if failures >= lockout.MaxFailures {
+		return ErrAccountLocked
+	}
+
+	return nil
+}
+
+// mfaLoginMethods are counted apart from the first factor, otherwise every
+// correct password would start a fresh series of code guesses. A passkey
+// assertion is a second factor as well, a passwordless passkey login passes
+// it too.
+var mfaLoginMethods = []string{LoginMethodTOTP, LoginMethodRecoveryCode, LoginMethodPasskey}
+
+// CheckMFALockout refuses the second factor step once too many codes were
+// wrong within the lockout window since the last passed second factor.
+func (p AuthProvider) CheckMFALockout(ctx context.Context, userID string) error {
+	lockout := p.conf.Security.Lockout
+	if lockout.MaxFailures <= 0 {
+		return nil
+	}
+
+	failures, err := p.repository.CountLoginFailuresByMethod(ctx, userID, mfaLoginMethods, time.Now().Add(-lockout.Window))
+	if err != nil {
+		return fmt.Errorf("failed to count mfa failures: %w", err)
+	}
+	if failures >= lockout.MaxFailures {
+		return ErrAccountLocked
+	}
+
+	return nil
+}
+
+// LoginPasskey applies the lockout to a passwordless passkey login and adds
+// it to the login history. Failed assertions are not recorded, the user
+// handle they carry is not authenticated.
+func (p AuthProvider) LoginPasskey(ctx context.Context, userID string, client ClientInfo) error {
+	if err := p.checkLockout(ctx, userID); err != nil {
+		return err
+	}
+
+	p.recordLoginAttempt(ctx, userID, "", LoginMethodPasskey, true, client)
+	return nil
+}
+
+// RecordMFAAttempt adds the second factor step of a login to the login
+// history, failures count towards CheckMFALockout.
+func (p AuthProvider) RecordMFAAttempt(ctx context.Context, userID, method string, succeeded bool, client ClientInfo) {
+	p.recordLoginAttempt(ctx, userID, "", method, succeeded, client)
+}
+
+// recordLoginAttempt never fails the login itself, a missing history row is
+// only logged.
+func (p AuthProvider) recordLoginAttempt(ctx context.Context, userID, login, method string, succeeded bool, client ClientInfo) {
+	err := p.repository.CreateLoginAttempt(ctx, models.LoginAttempt{
+		UserID:    sql.NullString{String: userID, Valid: userID != ""},
+		Login:     login,
+		Method:    method,
+		Succeeded: succeeded,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	})
+	if err != nil {
+		log.Default().Println("[ERR] record login attempt:", err.Error())
+	}
+}
@@ -1,6 +1,10 @@
 package auth
 
-import "github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+import (
+	"encoding/json"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
 
 type RegisterUserData struct {
 	Username  string
@@ -20,25 +24,45 @@ func ProviderRegisterReq2DB(user RegisterUserData) models.RegisterUserData {
 	}
 }
 
+// Login...
+
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodEmailLink = "email_link"
+	LoginMethodEmailCode = "email_code"
+	LoginMethodPasskey   = "passkey"
+	// Second factor steps of a login.
+	LoginMethodTOTP         = "totp"
+	LoginMethodRecoveryCode = "recovery_code"
+)
+
+// ClientInfo describes where a login attempt came from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
 // User...
 
 type User struct {
-	ID        string
-	Username  string
-	Email     string
-	FirstName string
-	LastName  string
-	CreatedAt string
+	ID           string
+	Username     string
+	Email        string
+	FirstName    string
+	LastName     string
+	CreatedAt    string
+	UniversityID string
 }
 
 func DBUser2Provider(user models.User) User {
 	return User{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		CreatedAt: user.CreatedAt,
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		CreatedAt:    user.CreatedAt,
+		UniversityID: user.UniversityID,
 	}
 }
 
@@ -79,3 +103,339 @@ func DBTeacher2Provider(teacher models.Teacher) Teacher {
 		User:         DBUser2Provider(teacher.User),
 	}
 }
+
+// Profile...
+
+// Profile is everything the service knows about the user: the account, its
+// roles and the student and teacher profiles, if any.
+type Profile struct {
+	User    User
+	Roles   []string
+	Student *Student
+	Teacher *Teacher
+}
+
+// ProfileUpdate holds the fields a user may edit. Nil fields are unchanged.
+type ProfileUpdate struct {
+	FirstName *string
+	LastName  *string
+	Degree    *string
+}
+
+func ProviderProfileUpdate2DB(update ProfileUpdate) models.ProfileUpdate {
+	return models.ProfileUpdate{
+		FirstName: update.FirstName,
+		LastName:  update.LastName,
+		Degree:    update.Degree,
+	}
+}
+
+// Username history...
+
+type UsernameHistory struct {
+	Username      string
+	ChangedAt     string
+	ReservedUntil string
+}
+
+func DBUsernameHistory2Provider(entry models.UsernameHistory) UsernameHistory {
+	return UsernameHistory{
+		Username:      entry.Username,
+		ChangedAt:     entry.ChangedAt,
+		ReservedUntil: entry.ReservedUntil,
+	}
+}
+
+// IdentityConflict is an account whose username or email collides with an
+// older account once case and Unicode normalization are ignored.
+type IdentityConflict struct {
+	ID         string
+	UserID     string
+	Field      string
+	Value      string
+	KeptUserID string
+	DetectedAt string
+}
+
+func DBIdentityConflict2Provider(conflict models.IdentityConflict) IdentityConflict {
+	return IdentityConflict(conflict)
+}
+
+// Roles and permissions...
+
+type Role struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+func DBRole2Provider(role models.Role) Role {
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return Role{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+	}
+}
+
+type Permission struct {
+	Name        string
+	Description string
+}
+
+const (
+	ScopeGlobal     = models.ScopeGlobal
+	ScopeUniversity = models.ScopeUniversity
+	ScopeGroup      = models.ScopeGroup
+)
+
+// RoleScope is where a role assignment applies. ID is empty for global
+// assignments.
+type RoleScope struct {
+	Type string
+	ID   string
+}
+
+// RoleAssignment is a role held in a scope. GrantedBy is empty for roles
+// obtained through activation keys, ExpiresAt for permanent assignments.
+type RoleAssignment struct {
+	UserID    string
+	Role      string
+	Scope     RoleScope
+	GrantedAt string
+	GrantedBy string
+	ExpiresAt string
+}
+
+func DBRoleAssignment2Provider(assignment models.RoleAssignment) RoleAssignment {
+	return RoleAssignment{
+		UserID:    assignment.UserID,
+		Role:      assignment.Role,
+		Scope:     RoleScope(assignment.RoleScope),
+		GrantedAt: assignment.GrantedAt,
+		GrantedBy: assignment.GrantedBy.String,
+		ExpiresAt: assignment.ExpiresAt.String,
+	}
+}
+
+// RoleMember is a user holding a role globally.
+type RoleMember struct {
+	UserID    string
+	Username  string
+	Email     string
+	Active    bool
+	GrantedAt string
+	GrantedBy string
+	ExpiresAt string
+}
+
+func DBRoleMember2Provider(member models.RoleMember) RoleMember {
+	return RoleMember{
+		UserID:    member.UserID,
+		Username:  member.Username,
+		Email:     member.Email,
+		Active:    member.Active,
+		GrantedAt: member.GrantedAt,
+		GrantedBy: member.GrantedBy.String,
+		ExpiresAt: member.ExpiresAt.String,
+	}
+}
+
+// KeyRequest is the profile an activation key is asked to grant. GroupID and
+// EnrollmentYear are set for students, Degree for teachers.
+type KeyRequest struct {
+	Role           string
+	GroupID        string
+	UniversityID   string
+	EnrollmentYear int
+	Degree         string
+}
+
+// RoleRequest asks for the student or teacher role with the attributes an
+// activation key would carry. GroupID and EnrollmentYear are set for
+// students, Degree for teachers.
+type RoleRequest struct {
+	ID             string
+	UserID         string
+	Role           string
+	UniversityID   string
+	GroupID        string
+	EnrollmentYear int
+	Degree         string
+	Comment        string
+	Status         string
+	ReviewedBy     string
+	ReviewComment  string
+	ReviewedAt     string
+	CreatedAt      string
+}
+
+func DBRoleRequest2Provider(request models.RoleRequest) RoleRequest {
+	return RoleRequest{
+		ID:             request.ID,
+		UserID:         request.UserID,
+		Role:           request.Role,
+		UniversityID:   request.UniversityID,
+		GroupID:        request.GroupID.String,
+		EnrollmentYear: int(request.EnrollmentYear.Int64),
+		Degree:         request.Degree.String,
+		Comment:        request.Comment,
+		Status:         request.Status,
+		ReviewedBy:     request.ReviewedBy.String,
+		ReviewComment:  request.ReviewComment.String,
+		ReviewedAt:     request.ReviewedAt.String,
+		CreatedAt:      request.CreatedAt,
+	}
+}
+
+// Resource locates the object of a permission check. Scoped role assignments
+// grant their permissions for resources in the same university or group.
+// OwnerID is set for user records. The zero Resource is only covered by
+// global assignments.
+type Resource struct {
+	UniversityID string
+	GroupID      string
+	OwnerID      string
+}
+
+// EffectivePermissions lists what the user's roles grant. Global permissions
+// apply everywhere, scoped ones only within their university or group.
+type EffectivePermissions struct {
+	Global []string
+	Scoped []ScopedPermission
+}
+
+type ScopedPermission struct {
+	Permission string
+	Scope      RoleScope
+}
+
+// Data export...
+
+// DataExport is everything stored about a user, see ExportUserData.
+type DataExport struct {
+	User            UserRecord
+	Roles           []string
+	Student         *Student
+	Teacher         *Teacher
+	LoginHistory    []LoginAttempt
+	UsernameHistory []UsernameHistory
+	EmailChanges    []EmailChangeRequest
+	ActivationKeys  []KeyRedemption
+	IssuedKeys      []KeyIssuance
+	RoleRequests    []RoleRequest
+	AuditEvents     []AuditRecord
+}
+
+type UserRecord struct {
+	User
+	SessionsRevokedAt string
+	DeactivatedAt     string
+	DeletedAt         string
+}
+
+func DBUserRecord2Provider(user models.UserRecord) UserRecord {
+	return UserRecord{
+		User:              DBUser2Provider(user.User),
+		SessionsRevokedAt: user.SessionsRevokedAt.String,
+		DeactivatedAt:     user.DeactivatedAt.String,
+		DeletedAt:         user.DeletedAt.String,
+	}
+}
+
+type LoginAttempt struct {
+	Login     string
+	Method    string
+	Succeeded bool
+	IP        string
+	UserAgent string
+	CreatedAt string
+}
+
+func DBLoginAttempt2Provider(attempt models.LoginAttempt) LoginAttempt {
+	return LoginAttempt{
+		Login:     attempt.Login,
+		Method:    attempt.Method,
+		Succeeded: attempt.Succeeded,
+		IP:        attempt.IP,
+		UserAgent: attempt.UserAgent,
+		CreatedAt: attempt.CreatedAt,
+	}
+}
+
+type EmailChangeRequest struct {
+	OldEmail    string
+	NewEmail    string
+	CreatedAt   string
+	ExpiresAt   string
+	ConfirmedAt string
+	CancelledAt string
+}
+
+func DBEmailChangeRequest2Provider(request models.EmailChangeRequest) EmailChangeRequest {
+	return EmailChangeRequest{
+		OldEmail:    request.OldEmail,
+		NewEmail:    request.NewEmail,
+		CreatedAt:   request.CreatedAt,
+		ExpiresAt:   request.ExpiresAt,
+		ConfirmedAt: request.ConfirmedAt.String,
+		CancelledAt: request.CancelledAt.String,
+	}
+}
+
+type KeyRedemption struct {
+	Role       string
+	KeyID      string
+	Attributes json.RawMessage
+	RedeemedAt string
+}
+
+func DBKeyRedemption2Provider(redemption models.KeyRedemption) KeyRedemption {
+	return KeyRedemption{
+		Role:       redemption.Role,
+		KeyID:      redemption.KeyID.String,
+		Attributes: redemption.Attributes,
+		RedeemedAt: redemption.RedeemedAt,
+	}
+}
+
+type KeyIssuance struct {
+	Role       string
+	KeyID      string
+	Attributes json.RawMessage
+	IssuedAt   string
+}
+
+func DBKeyIssuance2Provider(issuance models.KeyIssuance) KeyIssuance {
+	return KeyIssuance{
+		Role:       issuance.Role,
+		KeyID:      issuance.KeyID,
+		Attributes: issuance.Attributes,
+		IssuedAt:   issuance.IssuedAt,
+	}
+}
+
+type AuditRecord struct {
+	ID        string
+	ActorID   string
+	SubjectID string
+	Action    string
+	Details   json.RawMessage
+	IP        string
+	CreatedAt string
+}
+
+func DBAuditEvent2Provider(event models.AuditEvent) AuditRecord {
+	return AuditRecord{
+		ID:        event.ID,
+		ActorID:   event.ActorID.String,
+		SubjectID: event.SubjectID.String,
+		Action:    event.Action,
+		Details:   event.Details,
+		IP:        event.IP.String,
+		CreatedAt: event.CreatedAt,
+	}
+}
@@ -0,0 +1,290 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+// Permissions seeded by the migrations. Route guards refer to these, roles
+// map to them in role_permissions.
+const (
+	PermUsersRead              = "users:read"
+	PermUsersReadEmail         = "users:read:email"
+	PermUsersManage            = "users:manage"
+	PermUsersExport            = "users:export"
+	PermUsersAudit             = "users:audit"
+	PermUsersImpersonate       = "users:impersonate"
+	PermStudentsRead           = "students:read"
+	PermStudentsReadGroup      = "students:read:group"
+	PermTeachersRead           = "teachers:read"
+	PermTeachersReadUniversity = "teachers:read:university"
+	PermRolesRead              = "roles:read"
+	PermRolesManage            = "roles:manage"
+	PermRolesApprove           = "roles:approve"
+	PermKeysGenerate           = "keys:generate"
+	PermAuthzCheck             = "authz:check"
+)
+
+// builtinRoles are referenced by name in code and can not be deleted. The
+// admin role additionally keeps all of its permissions, otherwise the last
+// way to manage roles could be revoked.
+var builtinRoles = []string{"admin", "teacher", "student"}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+func (p AuthProvider) GetRoles(ctx context.Context) ([]Role, error) {
+	roles, err := p.repository.GetRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Role, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, DBRole2Provider(role))
+	}
+	return result, nil
+}
+
+func (p AuthProvider) GetPermissions(ctx context.Context) ([]Permission, error) {
+	permissions, err := p.repository.GetPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		result = append(result, Permission(permission))
+	}
+	return result, nil
+}
+
+func (p AuthProvider) CreateRole(ctx context.Context, role, description string) error {
+	if !roleNamePattern.MatchString(role) {
+		return fmt.Errorf("%w: name must be 2 to 32 lowercase letters, digits, '_' or '-'", ErrInvalidRole)
+	}
+
+	created, err := p.repository.CreateRole(ctx, role, description)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+	if !created {
+		return ErrRoleExists
+	}
+	return nil
+}
+
+func (p AuthProvider) DeleteRole(ctx context.Context, role string) error {
+	if slices.Contains(builtinRoles, role) {
+		return ErrBuiltinRole
+	}
+
+	deleted, err := p.repository.DeleteRole(ctx, role)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	if !deleted {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+func (p AuthProvider) GrantPermission(ctx context.Context, role, permission string) error {
+	if err := p.checkRoleAndPermission(ctx, role, permission); err != nil {
+		return err
+	}
+
+	if err := p.repository.GrantPermission(ctx, role, permission); err != nil {
+		return fmt.Errorf("failed to grant permission: %w", err)
+	}
+	return nil
+}
+
+func (p AuthProvider) RevokePermission(ctx context.Context, role, permission string) error {
+	if role == "admin" {
+		return ErrBuiltinRole
+	}
+	if err := p.checkRoleAndPermission(ctx, role, permission); err != nil {
+		return err
+	}
+
+	if _, err := p.repository.RevokePermission(ctx, role, permission); err != nil {
+		return fmt.Errorf("failed to revoke permission: %w", err)
+	}
+	return nil
+}
+
+// GetUserPermissions resolves the effective permissions of the user, i.e.
+// the union of the permissions of all of their role assignments.
+func (p AuthProvider) GetUserPermissions(ctx context.Context, userID string) (EffectivePermissions, error) {
+	exists, err := p.repository.FindUserByID(ctx, userID)
+	if err != nil || !exists {
+		return EffectivePermissions{}, ErrUserNotFound
+	}
+
+	permissions, err := p.repository.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return EffectivePermissions{}, err
+	}
+
+	result := EffectivePermissions{Global: []string{}, Scoped: []ScopedPermission{}}
+	for _, permission := range permissions {
+		if permission.Type == ScopeGlobal {
+			result.Global = append(result.Global, permission.Permission)
+			continue
+		}
+		result.Scoped = append(result.Scoped, ScopedPermission{
+			Permission: permission.Permission,
+			Scope:      RoleScope(permission.RoleScope),
+		})
+	}
+	return result, nil
+}
+
+// HasPermission reports whether the user may perform the permission on the
+// resource, either through a global role assignment or one scoped to the
+// resource's university or group.
+func (p AuthProvider) HasPermission(ctx context.Context, userID, permission string, resource Resource) (bool, error) {
+	return p.repository.HasPermission(ctx, userID, permission, resource.UniversityID, resource.GroupID)
+}
+
+// GrantRole assigns the role to the user within the scope, permanently when
+// expiresAt is zero. Student and teacher roles come with a profile row and
+// are normally obtained through activation keys; here they can only be
+// extended to further scopes.
+func (p AuthProvider) GrantRole(ctx context.Context, userID, role string, scope RoleScope, grantedBy string, expiresAt time.Time) error {
+	if err := validateScope(scope); err != nil {
+		return err
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		return ErrInvalidExpiry
+	}
+
+	exists, err := p.repository.FindUserByID(ctx, userID)
+	if err != nil || !exists {
+		return ErrUserNotFound
+	}
+
+	if exists, err := p.repository.RoleExists(ctx, role); err != nil {
+		return err
+	} else if !exists {
+		return ErrRoleNotFound
+	}
+
+	if role == "student" || role == "teacher" {
+		if activated, err := p.repository.CheckUserRole(ctx, userID, role); err != nil {
+			return err
+		} else if !activated {
+			return ErrRoleRequiresActivation
+		}
+	}
+
+	granted, err := p.repository.GrantRole(ctx, models.RoleGrant{
+		UserID:    userID,
+		Role:      role,
+		RoleScope: models.RoleScope(scope),
+		GrantedBy: sql.NullString{String: grantedBy, Valid: grantedBy != ""},
+		ExpiresAt: sql.NullTime{Time: expiresAt, Valid: !expiresAt.IsZero()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to grant role: %w", err)
+	}
+	if !granted {
+		return ErrRoleAlreadyGranted
+	}
+	return nil
+}
+
+// RevokeRole takes the role away from the user in the scope, or in every
+// scope when scope is nil, and returns the removed assignments. Losing the
+// last student or teacher assignment removes the profile row too.
+func (p AuthProvider) RevokeRole(ctx context.Context, userID, role string, scope *RoleScope) ([]RoleAssignment, error) {
+	var dbScope *models.RoleScope
+	if scope != nil {
+		if err := validateScope(*scope); err != nil {
+			return nil, err
+		}
+		dbScope = &models.RoleScope{Type: scope.Type, ID: scope.ID}
+	}
+
+	exists, err := p.repository.FindUserByID(ctx, userID)
+	if err != nil || !exists {
+		return nil, ErrUserNotFound
+	}
+
+	revoked, err := p.repository.RevokeRole(ctx, userID, role, dbScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke role: %w", err)
+	}
+	if len(revoked) == 0 {
+		return nil, ErrRoleNotGranted
+	}
+
+	result := make([]RoleAssignment, 0, len(revoked))
+	for _, assignment := range revoked {
+		result = append(result, DBRoleAssignment2Provider(assignment))
+	}
+	return result, nil
+}
+
+// ExpireRoles removes up to limit role assignments past their expiry and
+// records an audit event for each of them.
+func (p AuthProvider) ExpireRoles(ctx context.Context, limit int) ([]RoleAssignment, error) {
+	expired, err := p.repository.ExpireRoles(ctx, time.Now(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]RoleAssignment, 0, len(expired))
+	for _, assignment := range expired {
+		p.RecordAuditEvent(ctx, AuditEvent{
+			SubjectID: assignment.UserID,
+			Action:    AuditRoleExpired,
+			Details: map[string]any{
+				"role":       assignment.Role,
+				"scope_type": assignment.Type,
+				"scope_id":   assignment.ID,
+				"expires_at": assignment.ExpiresAt.String,
+			},
+		})
+		result = append(result, DBRoleAssignment2Provider(assignment))
+	}
+	return result, nil
+}
+
+func validateScope(scope RoleScope) error {
+	switch scope.Type {
+	case ScopeGlobal:
+		if scope.ID != "" {
+			return fmt.Errorf("%w: global scope takes no scope_id", ErrInvalidScope)
+		}
+	case ScopeUniversity, ScopeGroup:
+		if scope.ID == "" {
+			return fmt.Errorf("%w: scope_id is required", ErrInvalidScope)
+		}
+	default:
+		return fmt.Errorf("%w: scope_type must be global, university or group", ErrInvalidScope)
+	}
+	return nil
+}
+
+func (p AuthProvider) checkRoleAndPermission(ctx context.Context, role, permission string) error {
+	if exists, err := p.repository.RoleExists(ctx, role); err != nil {
+		return err
+	} else if !exists {
+		return ErrRoleNotFound
+	}
+
+	if exists, err := p.repository.PermissionExists(ctx, permission); err != nil {
+		return err
+	} else if !exists {
+		return ErrPermissionNotFound
+	}
+
+	return nil
+}
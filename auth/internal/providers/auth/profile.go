@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxProfileFieldLength = 100
+
+func (p AuthProvider) GetProfile(ctx context.Context, userID string) (Profile, error) {
+	user, err := p.repository.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Profile{}, ErrUserNotFound
+		}
+		return Profile{}, err
+	}
+
+	roles, err := p.repository.GetUserRoles(ctx, userID)
+	if err != nil {
+		return Profile{}, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	profile := Profile{
+		User:  DBUser2Provider(user),
+		Roles: roles,
+	}
+
+	if slices.Contains(roles, "student") {
+		student, err := p.repository.GetStudentByID(ctx, userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return Profile{}, fmt.Errorf("failed to get student profile: %w", err)
+		}
+		if err == nil {
+			s := DBStudent2Provider(student)
+			profile.Student = &s
+		}
+	}
+
+	if slices.Contains(roles, "teacher") {
+		teacher, err := p.repository.GetTeacherByID(ctx, userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return Profile{}, fmt.Errorf("failed to get teacher profile: %w", err)
+		}
+		if err == nil {
+			t := DBTeacher2Provider(teacher)
+			profile.Teacher = &t
+		}
+	}
+
+	return profile, nil
+}
+
+// UpdateProfile validates and applies the changes and returns the updated
+// profile. Only teachers may set a degree.
+func (p AuthProvider) UpdateProfile(ctx context.Context, userID string, update ProfileUpdate) (Profile, error) {
+	var err error
+	if update.FirstName, err = normalizeProfileField("first_name", update.FirstName); err != nil {
+		return Profile{}, err
+	}
+	if update.LastName, err = normalizeProfileField("last_name", update.LastName); err != nil {
+		return Profile{}, err
+	}
+	if update.Degree, err = normalizeProfileField("degree", update.Degree); err != nil {
+		return Profile{}, err
+	}
+
+	if update.Degree != nil {
+		isTeacher, err := p.repository.CheckUserRole(ctx, userID, "teacher")
+		if err != nil {
+			return Profile{}, fmt.Errorf("failed to check role: %w", err)
+		}
+		if !isTeacher {
+			return Profile{}, fmt.Errorf("%w: degree can only be set by teachers", ErrInvalidProfile)
+		}
+	}
+
+	updated, err := p.repository.UpdateProfile(ctx, userID, ProviderProfileUpdate2DB(update))
+	if err != nil {
+		return Profile{}, fmt.Errorf("failed to update profile: %w", err)
+	}
+	if !updated {
+		return Profile{}, ErrUserNotFound
+	}
+
+	return p.GetProfile(ctx, userID)
+}
+
+func normalizeProfileField(name string, value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	v := strings.TrimSpace(*value)
+	switch {
+	case v == "":
+		return nil, fmt.Errorf("%w: %s must not be empty", ErrInvalidProfile, name)
+	case utf8.RuneCountInString(v) > maxProfileFieldLength:
+		return nil, fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidProfile, name, maxProfileFieldLength)
+	case strings.ContainsFunc(v, unicode.IsControl):
+		return nil, fmt.Errorf("%w: %s contains invalid characters", ErrInvalidProfile, name)
+	}
+
+	return &v, nil
+}
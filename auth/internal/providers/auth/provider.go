@@ -6,21 +6,27 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/vladlim/auth-service-practice/auth/internal/clients/mailer"
+	"github.com/vladlim/auth-service-practice/auth/internal/config"
+	"github.com/vladlim/auth-service-practice/auth/internal/policy"
 	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Repository interface {
-	CreateUser(ctx context.Context, user models.RegisterUserData) (string, error)
+	RegisterUser(ctx context.Context, user models.RegisterUserData, activation *models.RoleActivation, redemption *models.KeyRedemption) (string, bool, error)
+	ActivateRole(ctx context.Context, userID string, activation models.RoleActivation, redemption models.KeyRedemption) (bool, bool, error)
+	CreateAdmin(ctx context.Context, user models.RegisterUserData) (string, error)
+	ResetPassword(ctx context.Context, userID, passwordHash string) (bool, error)
 	FindUserByUsername(ctx context.Context, username string) (string, string, error)
 	FindUserByEmail(ctx context.Context, email string) (string, string, error)
 	FindUserByID(ctx context.Context, userID string) (bool, error)
+	GetPasswordHash(ctx context.Context, userID string) (string, error)
 
-	CreateStudent(ctx context.Context, userID, groupID, universityID string, enrollmentYear int) error
-	CreateTeacher(ctx context.Context, userID, universityID, degree string) error
-	AddUserRole(ctx context.Context, userID, role string) error
 	CheckUserRole(ctx context.Context, userID, role string) (bool, error)
 
 	GetUserByID(ctx context.Context, userID string) (models.User, error)
@@ -30,29 +36,140 @@ type Repository interface {
 	GetTeacherByID(ctx context.Context, userID string) (models.Teacher, error)
 	GetTeachersByUni(ctx context.Context, uniID string) ([]models.Teacher, error)
 	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	UpdateProfile(ctx context.Context, userID string, update models.ProfileUpdate) (bool, error)
+
+	CreateLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error
+	CountLoginFailures(ctx context.Context, userID string, since time.Time) (int, error)
+	CountLoginFailuresByMethod(ctx context.Context, userID string, methods []string, since time.Time) (int, error)
+	CreateEmailLoginToken(ctx context.Context, userID, method, secretHash string, expiresAt time.Time) error
+	RevokeEmailLoginTokens(ctx context.Context, userID string) error
+	ConsumeEmailLoginLink(ctx context.Context, secretHash string) (string, error)
+	ConsumeEmailLoginCode(ctx context.Context, userID, secretHash string, maxAttempts int) (bool, error)
+	IncrementEmailLoginAttempts(ctx context.Context, userID string) error
+
+	CreateEmailChangeRequest(ctx context.Context, userID, oldEmail, newEmail, confirmHash, cancelHash string, expiresAt time.Time) error
+	CancelPendingEmailChanges(ctx context.Context, userID string) error
+	CancelEmailChange(ctx context.Context, cancelHash string) (string, error)
+	ConfirmEmailChange(ctx context.Context, confirmHash string) (models.EmailChange, bool, error)
+
+	IsUsernameReserved(ctx context.Context, username, userID string) (bool, error)
+	ChangeUsername(ctx context.Context, userID, username string, reservedUntil time.Time) (bool, error)
+	GetUsernameHistory(ctx context.Context, userID string) ([]models.UsernameHistory, error)
+	GetIdentityConflicts(ctx context.Context) ([]models.IdentityConflict, error)
+	GetIdentityConflict(ctx context.Context, conflictID string) (models.IdentityConflict, error)
+	ResolveIdentityConflict(ctx context.Context, conflictID, value string) (models.IdentityConflict, bool, error)
+
+	IsUserActive(ctx context.Context, userID string) (bool, error)
+	DeactivateUser(ctx context.Context, userID string) (bool, error)
+	SoftDeleteUser(ctx context.Context, userID string) (bool, error)
+	ReactivateUser(ctx context.Context, userID string) (bool, error)
+
+	CreateAuditEvent(ctx context.Context, event models.AuditEvent) error
+	GetAuditEventsByUser(ctx context.Context, userID string) ([]models.AuditEvent, error)
+	CreateKeyIssuance(ctx context.Context, issuance models.KeyIssuance) error
+	CreateLimitedKeyIssuance(ctx context.Context, issuance models.KeyIssuance, limit int, since time.Time) (bool, error)
+	GetKeyIssuances(ctx context.Context, issuedBy string) ([]models.KeyIssuance, error)
+	GetKeyRedemptions(ctx context.Context, userID string) ([]models.KeyRedemption, error)
+
+	GetUserRecord(ctx context.Context, userID string) (models.UserRecord, error)
+	GetLoginAttempts(ctx context.Context, userID string) ([]models.LoginAttempt, error)
+	GetEmailChangeRequests(ctx context.Context, userID string) ([]models.EmailChangeRequest, error)
+
+	GetRoles(ctx context.Context) ([]models.Role, error)
+	GetPermissions(ctx context.Context) ([]models.Permission, error)
+	RoleExists(ctx context.Context, role string) (bool, error)
+	PermissionExists(ctx context.Context, permission string) (bool, error)
+	CreateRole(ctx context.Context, role, description string) (bool, error)
+	DeleteRole(ctx context.Context, role string) (bool, error)
+	GrantPermission(ctx context.Context, role, permission string) error
+	RevokePermission(ctx context.Context, role, permission string) (bool, error)
+	GetUserPermissions(ctx context.Context, userID string) ([]models.ScopedPermission, error)
+	HasPermission(ctx context.Context, userID, permission, universityID, groupID string) (bool, error)
+	GetRoleAssignments(ctx context.Context, userID string) ([]models.RoleAssignment, error)
+	GrantRole(ctx context.Context, grant models.RoleGrant) (bool, error)
+	GetRoleMembers(ctx context.Context, role string) ([]models.RoleMember, error)
+	RevokeRole(ctx context.Context, userID, role string, scope *models.RoleScope) ([]models.RoleAssignment, error)
+	ExpireRoles(ctx context.Context, now time.Time, limit int) ([]models.RoleAssignment, error)
+
+	GetTeacherGroups(ctx context.Context, userID string) ([]string, error)
+	AddTeacherGroup(ctx context.Context, userID, groupID string) (bool, error)
+	RemoveTeacherGroup(ctx context.Context, userID, groupID string) (bool, error)
+	GetUserAffiliation(ctx context.Context, userID string) (models.Affiliation, error)
+	GetGroupUniversities(ctx context.Context, groupID string) ([]string, error)
+	GetRolePermissions(ctx context.Context, role string) ([]string, error)
+
+	CreateRoleRequest(ctx context.Context, request models.RoleRequest) (models.RoleRequest, error)
+	GetRoleRequest(ctx context.Context, requestID string) (models.RoleRequest, error)
+	GetRoleRequests(ctx context.Context, status, universityID string) ([]models.RoleRequest, error)
+	GetUserRoleRequests(ctx context.Context, userID string) ([]models.RoleRequest, error)
+	ApproveRoleRequest(ctx context.Context, review models.RoleReview) (models.RoleRequest, bool, error)
+	RejectRoleRequest(ctx context.Context, review models.RoleReview) (models.RoleRequest, error)
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg mailer.Message) error
+}
+
+// Validator normalizes usernames and emails and rejects those that break the
+// configured rules.
+type Validator interface {
+	Username(username string) (string, error)
+	Email(email string) (string, error)
+}
+
+// Policies hands out the attribute-based policies currently in effect.
+type Policies interface {
+	Policies() *policy.Set
 }
 
 type AuthProvider struct {
 	repository Repository
+	mailer     Mailer
+	validator  Validator
+	policies   Policies
+	conf       config.Config
 }
 
-func New(repository Repository) AuthProvider {
+func New(repository Repository, mailer Mailer, validator Validator, policies Policies, conf config.Config) AuthProvider {
 	return AuthProvider{
 		repository: repository,
+		mailer:     mailer,
+		validator:  validator,
+		policies:   policies,
+		conf:       conf,
 	}
 }
 
-func (p AuthProvider) RegisterUser(ctx context.Context, user RegisterUserData) (string, error) {
-	userConv := ProviderRegisterReq2DB(user)
-	if _, _, err := p.repository.FindUserByUsername(ctx, userConv.Username); err == nil {
-		return "", ErrUsernameExists
-	} else if err != sql.ErrNoRows {
+// RegisterUser creates an account under the configured registration mode.
+// When key holds the claims of a validated activation key, the role it grants
+// is activated in the same transaction.
+func (p AuthProvider) RegisterUser(ctx context.Context, user RegisterUserData, key jwt.MapClaims) (string, error) {
+	var err error
+	if user.Username, err = p.validateUsername(user.Username); err != nil {
+		return "", err
+	}
+	if user.Email, err = p.validateEmail(user.Email); err != nil {
 		return "", err
 	}
 
-	if _, _, err := p.repository.FindUserByEmail(ctx, userConv.Email); err == nil {
-		return "", ErrEmailExists
-	} else if err != sql.ErrNoRows {
+	universityID, err := p.checkRegistrationPolicy(user.Email, key)
+	if err != nil {
+		return "", err
+	}
+
+	var activation *models.RoleActivation
+	var redemption *models.KeyRedemption
+	if key != nil {
+		parsed, err := roleActivationFromKey(key)
+		if err != nil {
+			return "", err
+		}
+		activation = &parsed
+		consumed := keyRedemption(parsed.Role, key)
+		redemption = &consumed
+	}
+
+	if err := p.checkIdentityAvailable(ctx, user.Username, user.Email); err != nil {
 		return "", err
 	}
 
@@ -63,12 +180,47 @@ func (p AuthProvider) RegisterUser(ctx context.Context, user RegisterUserData) (
 
 	user.Password = string(hashedPassword)
 
-	userID, err := p.repository.CreateUser(ctx, ProviderRegisterReq2DB(user))
+	userConv := ProviderRegisterReq2DB(user)
+	userConv.UniversityID = universityID
+	userID, registered, err := p.repository.RegisterUser(ctx, userConv, activation, redemption)
+	if err != nil {
+		return "", err
+	}
+	if !registered {
+		return "", ErrActivationKeyRedeemed
+	}
+
+	return userID, nil
+}
+
+// checkIdentityAvailable makes sure that nobody uses or reserved the
+// username and that the email is free.
+func (p AuthProvider) checkIdentityAvailable(ctx context.Context, username, email string) error {
+	if _, _, err := p.repository.FindUserByUsername(ctx, username); err == nil {
+		return ErrUsernameExists
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	if reserved, err := p.repository.IsUsernameReserved(ctx, username, ""); err != nil {
+		return err
+	} else if reserved {
+		return ErrUsernameReserved
+	}
 
-	return userID, err
+	if _, _, err := p.repository.FindUserByEmail(ctx, email); err == nil {
+		return ErrEmailExists
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	return nil
 }
 
-func (p AuthProvider) LoginUser(ctx context.Context, login, password string) (string, error) {
+// LoginUser fails with ErrInvalidCredentials for unknown logins and wrong
+// passwords alike. Unknown logins are still checked against a dummy hash so
+// that response timing does not reveal which accounts exist.
+func (p AuthProvider) LoginUser(ctx context.Context, login, password string, client ClientInfo) (string, error) {
 	var userID, userPassword string
 	var err error
 	if strings.Contains(login, "@") {
@@ -79,69 +231,60 @@ func (p AuthProvider) LoginUser(ctx context.Context, login, password string) (st
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", ErrUserNotFound
+			bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+			p.recordLoginAttempt(ctx, "", login, LoginMethodPassword, false, client)
+			return "", ErrInvalidCredentials
 		}
 		return "", err
 	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(userPassword), []byte(password)); err != nil {
-		return "", ErrIncorrectPassword
-	}
+	passwordErr := bcrypt.CompareHashAndPassword([]byte(userPassword), []byte(password))
 
-	return userID, nil
-}
-
-// Activate keys...
-
-func (p AuthProvider) ActivateStudent(ctx context.Context, userID string, claims jwt.MapClaims) error {
-	groupID, ok1 := claims["group_id"].(string)
-	universityID, ok2 := claims["university_id"].(string)
-	enrollmentYear, ok3 := claims["enrollment_year"].(float64)
-
-	if !ok1 || !ok2 || !ok3 {
-		return errors.New("invalid student key parameters")
+	if err := p.checkLockout(ctx, userID); err != nil {
+		return "", err
 	}
 
-	if activated, err := p.repository.CheckUserRole(ctx, userID, "student"); err != nil {
-		return fmt.Errorf("failed to check role activation: %w", err)
-	} else if activated {
-		return errors.New("student role already activated")
+	if passwordErr != nil {
+		p.recordLoginAttempt(ctx, userID, login, LoginMethodPassword, false, client)
+		return "", ErrInvalidCredentials
 	}
 
-	if err := p.repository.CreateStudent(ctx, userID, groupID, universityID, int(enrollmentYear)); err != nil {
-		return fmt.Errorf("failed to create student: %w", err)
-	}
+	p.recordLoginAttempt(ctx, userID, login, LoginMethodPassword, true, client)
+	return userID, nil
+}
 
-	if err := p.repository.AddUserRole(ctx, userID, "student"); err != nil {
-		return fmt.Errorf("failed to add student role: %w", err)
+// dummyPasswordHash is compared against when the login does not exist. It
+// uses the same cost as real hashes so both paths take the same time.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
 	}
+	return hash
+})
 
-	return nil
-}
-
-func (p AuthProvider) ActivateTeacher(ctx context.Context, userID string, claims jwt.MapClaims) error {
-	universityID, ok1 := claims["university_id"].(string)
-	degree, ok2 := claims["degree"].(string)
+// Activate keys...
 
-	if !ok1 || !ok2 {
-		return errors.New("invalid teacher key parameters")
+// ActivateKey activates the role granted by a validated activation key for
+// the user and consumes the key. It returns the activated role.
+func (p AuthProvider) ActivateKey(ctx context.Context, userID string, key jwt.MapClaims) (string, error) {
+	activation, err := roleActivationFromKey(key)
+	if err != nil {
+		return "", err
 	}
 
-	if activated, err := p.repository.CheckUserRole(ctx, userID, "teacher"); err != nil {
-		return fmt.Errorf("failed to check role activation: %w", err)
-	} else if activated {
-		return errors.New("teacher role already activated")
+	redeemed, activated, err := p.repository.ActivateRole(ctx, userID, activation, keyRedemption(activation.Role, key))
+	if err != nil {
+		return "", fmt.Errorf("failed to activate role: %w", err)
 	}
-
-	if err := p.repository.CreateTeacher(ctx, userID, universityID, degree); err != nil {
-		return fmt.Errorf("failed to create teacher: %w", err)
+	if !redeemed {
+		return "", ErrActivationKeyRedeemed
 	}
-
-	if err := p.repository.AddUserRole(ctx, userID, "teacher"); err != nil {
-		return fmt.Errorf("failed to add teacher role: %w", err)
+	if !activated {
+		return "", ErrRoleAlreadyActivated
 	}
 
-	return nil
+	return activation.Role, nil
 }
 
 // User Info...
@@ -200,8 +343,17 @@ func (p AuthProvider) GetStudentByID(ctx context.Context, userID string) (Studen
 	return DBStudent2Provider(student), err
 }
 
-func (p AuthProvider) GetStudents(ctx context.Context, groupIDs string) ([]models.Student, error) {
-	return p.repository.GetStudentsByGroup(ctx, groupIDs)
+func (p AuthProvider) GetStudents(ctx context.Context, groupIDs string) ([]Student, error) {
+	students, err := p.repository.GetStudentsByGroup(ctx, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Student, 0, len(students))
+	for _, student := range students {
+		result = append(result, DBStudent2Provider(student))
+	}
+	return result, nil
 }
 
 func (p AuthProvider) GetTeacherByID(ctx context.Context, userID string) (Teacher, error) {
@@ -221,10 +373,35 @@ func (p AuthProvider) GetTeacherByID(ctx context.Context, userID string) (Teache
 	return DBTeacher2Provider(teacher), err
 }
 
-func (p AuthProvider) GetTeachers(ctx context.Context, uniIDs string) ([]models.Teacher, error) {
-	return p.repository.GetTeachersByUni(ctx, uniIDs)
+func (p AuthProvider) GetTeachers(ctx context.Context, uniIDs string) ([]Teacher, error) {
+	teachers, err := p.repository.GetTeachersByUni(ctx, uniIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Teacher, 0, len(teachers))
+	for _, teacher := range teachers {
+		result = append(result, DBTeacher2Provider(teacher))
+	}
+	return result, nil
+}
+
+// GetUserRoles returns the user's role assignments with their scopes. A role
+// may be assigned several times, once per university or group.
+func (p AuthProvider) GetUserRoles(ctx context.Context, userID string) ([]RoleAssignment, error) {
+	assignments, err := p.repository.GetRoleAssignments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]RoleAssignment, 0, len(assignments))
+	for _, assignment := range assignments {
+		result = append(result, DBRoleAssignment2Provider(assignment))
+	}
+	return result, nil
 }
 
-func (p AuthProvider) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+// GetUserRoleNames returns the names of the user's roles, regardless of scope.
+func (p AuthProvider) GetUserRoleNames(ctx context.Context, userID string) ([]string, error) {
 	return p.repository.GetUserRoles(ctx, userID)
 }
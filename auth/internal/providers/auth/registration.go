@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+// Registration modes, see config.Registration.
+const (
+	RegistrationOpen   = "open"
+	RegistrationInvite = "invite"
+	RegistrationDomain = "domain"
+)
+
+// checkRegistrationPolicy decides whether the email may register under the
+// configured mode. In domain mode it also returns the university the email
+// domain is mapped to, if any. Unknown modes reject everybody.
+func (p AuthProvider) checkRegistrationPolicy(email string, key jwt.MapClaims) (sql.NullString, error) {
+	conf := p.conf.Security.Registration
+
+	switch conf.Mode {
+	case "", RegistrationOpen:
+		return sql.NullString{}, nil
+	case RegistrationInvite:
+		if key == nil {
+			return sql.NullString{}, ErrActivationKeyRequired
+		}
+		return sql.NullString{}, nil
+	case RegistrationDomain:
+		domain := email[strings.LastIndexByte(email, '@')+1:]
+		for _, allowed := range conf.Domains {
+			name := strings.ToLower(allowed.Domain)
+			if domain == name || strings.HasSuffix(domain, "."+name) {
+				return sql.NullString{String: allowed.UniversityID, Valid: allowed.UniversityID != ""}, nil
+			}
+		}
+		return sql.NullString{}, ErrEmailDomainNotAllowed
+	default:
+		return sql.NullString{}, ErrRegistrationClosed
+	}
+}
+
+// roleActivationFromKey reads the profile attributes from the claims of an
+// already validated activation key. Keys without a jti could be redeemed any
+// number of times and are refused.
+func roleActivationFromKey(claims jwt.MapClaims) (models.RoleActivation, error) {
+	if keyID, _ := claims["jti"].(string); keyID == "" {
+		return models.RoleActivation{}, ErrInvalidActivationKey
+	}
+
+	role, _ := claims["role"].(string)
+	universityID, ok := claims["university_id"].(string)
+	if !ok {
+		return models.RoleActivation{}, ErrInvalidActivationKey
+	}
+
+	activation := models.RoleActivation{Role: role, UniversityID: universityID}
+	switch role {
+	case "student":
+		groupID, ok1 := claims["group_id"].(string)
+		enrollmentYear, ok2 := claims["enrollment_year"].(float64)
+		if !ok1 || !ok2 {
+			return models.RoleActivation{}, ErrInvalidActivationKey
+		}
+		activation.GroupID = groupID
+		activation.EnrollmentYear = int(enrollmentYear)
+	case "teacher":
+		degree, ok := claims["degree"].(string)
+		if !ok {
+			return models.RoleActivation{}, ErrInvalidActivationKey
+		}
+		activation.Degree = degree
+	default:
+		return models.RoleActivation{}, ErrInvalidActivationKey
+	}
+
+	return activation, nil
+}
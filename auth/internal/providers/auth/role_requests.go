@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/clients/mailer"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+const (
+	RoleRequestPending  = models.RoleRequestPending
+	RoleRequestApproved = models.RoleRequestApproved
+	RoleRequestRejected = models.RoleRequestRejected
+)
+
+const maxRoleRequestComment = 1000
+
+// RequestRole files a request for the student or teacher role, so that users
+// without an activation key can have the role approved instead.
+func (p AuthProvider) RequestRole(ctx context.Context, request RoleRequest) (RoleRequest, error) {
+	if err := validateRoleRequest(&request); err != nil {
+		return RoleRequest{}, err
+	}
+
+	exists, err := p.repository.FindUserByID(ctx, request.UserID)
+	if err != nil || !exists {
+		return RoleRequest{}, ErrUserNotFound
+	}
+
+	if activated, err := p.repository.CheckUserRole(ctx, request.UserID, request.Role); err != nil {
+		return RoleRequest{}, fmt.Errorf("failed to check role activation: %w", err)
+	} else if activated {
+		return RoleRequest{}, ErrRoleAlreadyActivated
+	}
+
+	created, err := p.repository.CreateRoleRequest(ctx, models.RoleRequest{
+		UserID:         request.UserID,
+		Role:           request.Role,
+		UniversityID:   request.UniversityID,
+		GroupID:        sql.NullString{String: request.GroupID, Valid: request.GroupID != ""},
+		EnrollmentYear: sql.NullInt64{Int64: int64(request.EnrollmentYear), Valid: request.EnrollmentYear != 0},
+		Degree:         sql.NullString{String: request.Degree, Valid: request.Degree != ""},
+		Comment:        request.Comment,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RoleRequest{}, ErrRoleRequestPending
+		}
+		return RoleRequest{}, fmt.Errorf("failed to create role request: %w", err)
+	}
+
+	return DBRoleRequest2Provider(created), nil
+}
+
+func (p AuthProvider) GetRoleRequest(ctx context.Context, requestID string) (RoleRequest, error) {
+	if !uuidPattern.MatchString(requestID) {
+		return RoleRequest{}, ErrRoleRequestNotFound
+	}
+
+	request, err := p.repository.GetRoleRequest(ctx, requestID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RoleRequest{}, ErrRoleRequestNotFound
+		}
+		return RoleRequest{}, err
+	}
+	return DBRoleRequest2Provider(request), nil
+}
+
+// GetRoleRequests lists role requests with the status in the university.
+// Empty filters match all requests.
+func (p AuthProvider) GetRoleRequests(ctx context.Context, status, universityID string) ([]RoleRequest, error) {
+	switch status {
+	case "", RoleRequestPending, RoleRequestApproved, RoleRequestRejected:
+	default:
+		return nil, fmt.Errorf("%w: status must be pending, approved or rejected", ErrInvalidRoleRequest)
+	}
+	if universityID != "" && !uuidPattern.MatchString(universityID) {
+		return nil, fmt.Errorf("%w: university_id must be a uuid", ErrInvalidRoleRequest)
+	}
+
+	requests, err := p.repository.GetRoleRequests(ctx, status, universityID)
+	if err != nil {
+		return nil, err
+	}
+	return dbRoleRequests2Provider(requests), nil
+}
+
+func (p AuthProvider) GetUserRoleRequests(ctx context.Context, userID string) ([]RoleRequest, error) {
+	requests, err := p.repository.GetUserRoleRequests(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return dbRoleRequests2Provider(requests), nil
+}
+
+// ApproveRoleRequest activates the requested role the way an activation key
+// would, within the same transaction that closes the request, and notifies
+// the user. A request for a role the user already has is rejected instead
+// and ErrRoleAlreadyActivated is returned.
+func (p AuthProvider) ApproveRoleRequest(ctx context.Context, requestID, reviewerID, comment string) (RoleRequest, error) {
+	review, err := p.roleReview(ctx, requestID, reviewerID, comment)
+	if err != nil {
+		return RoleRequest{}, err
+	}
+
+	approved, activated, err := p.repository.ApproveRoleRequest(ctx, review)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RoleRequest{}, ErrRoleRequestReviewed
+		}
+		return RoleRequest{}, fmt.Errorf("failed to approve role request: %w", err)
+	}
+	if !activated {
+		return RoleRequest{}, ErrRoleAlreadyActivated
+	}
+
+	request := DBRoleRequest2Provider(approved)
+	p.notifyRoleRequest(ctx, request)
+	return request, nil
+}
+
+func (p AuthProvider) RejectRoleRequest(ctx context.Context, requestID, reviewerID, comment string) (RoleRequest, error) {
+	if strings.TrimSpace(comment) == "" {
+		return RoleRequest{}, fmt.Errorf("%w: a comment is required to reject", ErrInvalidRoleRequest)
+	}
+
+	review, err := p.roleReview(ctx, requestID, reviewerID, comment)
+	if err != nil {
+		return RoleRequest{}, err
+	}
+
+	rejected, err := p.repository.RejectRoleRequest(ctx, review)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RoleRequest{}, ErrRoleRequestReviewed
+		}
+		return RoleRequest{}, fmt.Errorf("failed to reject role request: %w", err)
+	}
+
+	request := DBRoleRequest2Provider(rejected)
+	p.notifyRoleRequest(ctx, request)
+	return request, nil
+}
+
+func (p AuthProvider) roleReview(ctx context.Context, requestID, reviewerID, comment string) (models.RoleReview, error) {
+	if len(comment) > maxRoleRequestComment {
+		return models.RoleReview{}, fmt.Errorf("%w: comment is too long", ErrInvalidRoleRequest)
+	}
+
+	request, err := p.GetRoleRequest(ctx, requestID)
+	if err != nil {
+		return models.RoleReview{}, err
+	}
+	if request.UserID == reviewerID {
+		return models.RoleReview{}, ErrSelfReview
+	}
+
+	return models.RoleReview{RequestID: requestID, ReviewedBy: reviewerID, Comment: comment}, nil
+}
+
+// notifyRoleRequest tells the user about the decision. The decision stands
+// even if the mail can not be sent.
+func (p AuthProvider) notifyRoleRequest(ctx context.Context, request RoleRequest) {
+	user, err := p.repository.GetUserByID(ctx, request.UserID)
+	if err != nil {
+		log.Default().Printf("[ERR] notify role request %s: %s\n", request.ID, err.Error())
+		return
+	}
+
+	msg := mailer.Message{To: user.Email}
+	if request.Status == RoleRequestApproved {
+		msg.Subject = fmt.Sprintf("Your %s role request was approved", request.Role)
+		msg.Body = fmt.Sprintf("Your request for the %s role was approved. "+
+			"Sign in again for the role to show up in your account.", request.Role)
+	} else {
+		msg.Subject = fmt.Sprintf("Your %s role request was rejected", request.Role)
+		msg.Body = fmt.Sprintf("Your request for the %s role was rejected.", request.Role)
+	}
+	if request.ReviewComment != "" {
+		msg.Body += "\n\nComment from the reviewer:\n\n" + request.ReviewComment
+	}
+
+	if err := p.mailer.Send(ctx, msg); err != nil {
+		log.Default().Printf("[ERR] notify role request %s: %s\n", request.ID, err.Error())
+	}
+}
+
+// validateRoleRequest checks the attributes the role needs and drops the
+// ones it does not take.
+func validateRoleRequest(request *RoleRequest) error {
+	if !uuidPattern.MatchString(request.UniversityID) {
+		return fmt.Errorf("%w: university_id must be a uuid", ErrInvalidRoleRequest)
+	}
+	if len(request.Comment) > maxRoleRequestComment {
+		return fmt.Errorf("%w: comment is too long", ErrInvalidRoleRequest)
+	}
+
+	switch request.Role {
+	case "student":
+		if !uuidPattern.MatchString(request.GroupID) {
+			return fmt.Errorf("%w: group_id must be a uuid", ErrInvalidRoleRequest)
+		}
+		if request.EnrollmentYear < 1900 || request.EnrollmentYear > time.Now().Year()+1 {
+			return fmt.Errorf("%w: enrollment_year is out of range", ErrInvalidRoleRequest)
+		}
+		request.Degree = ""
+	case "teacher":
+		request.Degree = strings.TrimSpace(request.Degree)
+		if request.Degree == "" {
+			return fmt.Errorf("%w: degree is required", ErrInvalidRoleRequest)
+		}
+		request.GroupID, request.EnrollmentYear = "", 0
+	default:
+		return fmt.Errorf("%w: role must be student or teacher", ErrInvalidRoleRequest)
+	}
+	return nil
+}
+
+func dbRoleRequests2Provider(requests []models.RoleRequest) []RoleRequest {
+	result := make([]RoleRequest, 0, len(requests))
+	for _, request := range requests {
+		result = append(result, DBRoleRequest2Provider(request))
+	}
+	return result
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+const defaultUsernameReservation = 30 * 24 * time.Hour
+
+// ChangeUsername renames the user. The old username stays reserved for the
+// configured period so that nobody else can claim it; the user may take it
+// back in the meantime. It returns the username as stored, after
+// normalization.
+func (p AuthProvider) ChangeUsername(ctx context.Context, userID, username string) (string, error) {
+	username, err := p.validateUsername(username)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := p.repository.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	if user.Username == username {
+		return "", fmt.Errorf("%w: new username matches the current one", ErrInvalidUsername)
+	}
+
+	// Lookups ignore case, so a user changing only the case of their own
+	// name finds themselves here.
+	if id, _, err := p.repository.FindUserByUsername(ctx, username); err == nil && id != userID {
+		return "", ErrUsernameExists
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	if reserved, err := p.repository.IsUsernameReserved(ctx, username, userID); err != nil {
+		return "", err
+	} else if reserved {
+		return "", ErrUsernameReserved
+	}
+
+	// The transaction re-checks both conditions, a false here means another
+	// request took the name in between.
+	changed, err := p.repository.ChangeUsername(ctx, userID, username, time.Now().Add(p.usernameReservation()))
+	if err != nil {
+		return "", fmt.Errorf("failed to change username: %w", err)
+	}
+	if !changed {
+		return "", ErrUsernameExists
+	}
+
+	return username, nil
+}
+
+func (p AuthProvider) GetUsernameHistory(ctx context.Context, userID string) ([]UsernameHistory, error) {
+	exists, err := p.repository.FindUserByID(ctx, userID)
+	if err != nil || !exists {
+		return nil, ErrUserNotFound
+	}
+
+	history, err := p.repository.GetUsernameHistory(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]UsernameHistory, 0, len(history))
+	for _, entry := range history {
+		result = append(result, DBUsernameHistory2Provider(entry))
+	}
+	return result, nil
+}
+
+// GetIdentityConflicts lists accounts that were left without a canonical
+// username or email because an older account already had it.
+func (p AuthProvider) GetIdentityConflicts(ctx context.Context) ([]IdentityConflict, error) {
+	conflicts, err := p.repository.GetIdentityConflicts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]IdentityConflict, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		result = append(result, DBIdentityConflict2Provider(conflict))
+	}
+	return result, nil
+}
+
+// ResolveIdentityConflict replaces the colliding username or email of an open
+// conflict with value, after which the user can log in with it again. It
+// returns the conflict with Value set to the new value, as stored.
+func (p AuthProvider) ResolveIdentityConflict(ctx context.Context, conflictID, value string) (IdentityConflict, error) {
+	if !uuidPattern.MatchString(conflictID) {
+		return IdentityConflict{}, ErrConflictNotFound
+	}
+
+	conflict, err := p.repository.GetIdentityConflict(ctx, conflictID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return IdentityConflict{}, ErrConflictNotFound
+		}
+		return IdentityConflict{}, err
+	}
+
+	taken := ErrEmailExists
+	switch conflict.Field {
+	case models.IdentityFieldUsername:
+		if value, err = p.validateUsername(value); err != nil {
+			return IdentityConflict{}, err
+		}
+		if _, _, err := p.repository.FindUserByUsername(ctx, value); err == nil {
+			return IdentityConflict{}, ErrUsernameExists
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return IdentityConflict{}, err
+		}
+		if reserved, err := p.repository.IsUsernameReserved(ctx, value, conflict.UserID); err != nil {
+			return IdentityConflict{}, err
+		} else if reserved {
+			return IdentityConflict{}, ErrUsernameReserved
+		}
+		taken = ErrUsernameExists
+	case models.IdentityFieldEmail:
+		if value, err = p.validateEmail(value); err != nil {
+			return IdentityConflict{}, err
+		}
+		if _, _, err := p.repository.FindUserByEmail(ctx, value); err == nil {
+			return IdentityConflict{}, ErrEmailExists
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return IdentityConflict{}, err
+		}
+	}
+
+	// The transaction re-checks the conflict and the new value, a false here
+	// means another request took the value in between.
+	resolved, updated, err := p.repository.ResolveIdentityConflict(ctx, conflictID, value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return IdentityConflict{}, ErrConflictNotFound
+		}
+		return IdentityConflict{}, fmt.Errorf("failed to resolve identity conflict: %w", err)
+	}
+	if !updated {
+		return IdentityConflict{}, taken
+	}
+
+	resolved.Value = value
+	return DBIdentityConflict2Provider(resolved), nil
+}
+
+func (p AuthProvider) usernameReservation() time.Duration {
+	if period := p.conf.Security.Username.ReservationPeriod; period > 0 {
+		return period
+	}
+	return defaultUsernameReservation
+}
+
+func (p AuthProvider) validateUsername(username string) (string, error) {
+	username, err := p.validator.Username(username)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidUsername, err)
+	}
+	return username, nil
+}
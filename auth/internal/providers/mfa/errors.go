@@ -0,0 +1,18 @@
+package mfa
+
+import "errors"
+
+var (
+	ErrInvalidCode        = errors.New("invalid code")
+	ErrTOTPNotEnrolled    = errors.New("totp is not enrolled")
+	ErrTOTPAlreadyEnabled = errors.New("totp already enabled")
+	ErrTOTPGenerate       = errors.New("totp secret generation error")
+
+	ErrRecoveryCodesGenerate = errors.New("recovery codes generation error")
+
+	ErrInvalidPasskey  = errors.New("invalid passkey")
+	ErrPasskeyNotFound = errors.New("passkey not found")
+	ErrWebAuthnSession = errors.New("webauthn session not found or expired")
+
+	ErrMFARequired = errors.New("mfa is required for the user's role")
+)
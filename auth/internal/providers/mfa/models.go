@@ -0,0 +1,40 @@
+package mfa
+
+import "github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+	QRCode          []byte // PNG
+}
+
+// WebAuthn...
+
+type PasskeyUser struct {
+	ID          string
+	Name        string
+	DisplayName string
+}
+
+// WebAuthnChallenge holds the options for navigator.credentials.create/get
+// and the ID of the server-side ceremony session to send back on finish.
+type WebAuthnChallenge struct {
+	SessionID string
+	Options   any
+}
+
+type Passkey struct {
+	ID         string
+	Name       string
+	CreatedAt  string
+	LastUsedAt string
+}
+
+func DBWebAuthnCredential2Passkey(credential models.WebAuthnCredential) Passkey {
+	return Passkey{
+		ID:         credential.ID,
+		Name:       credential.Name,
+		CreatedAt:  credential.CreatedAt,
+		LastUsedAt: credential.LastUsedAt.String,
+	}
+}
@@ -0,0 +1,62 @@
+package mfa
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// RoleRequiresMFA reports whether holders of the role must have a second
+// factor before they get full-privilege tokens.
+func (p MFAProvider) RoleRequiresMFA(role string) bool {
+	return slices.Contains(p.requiredRoles, role)
+}
+
+// RequiresMFA reports whether any of the user's roles is covered by the
+// policy, regardless of whether the user has already enrolled.
+func (p MFAProvider) RequiresMFA(ctx context.Context, userID string) (bool, error) {
+	if len(p.requiredRoles) == 0 {
+		return false, nil
+	}
+
+	roles, err := p.repository.GetUserRoles(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	return slices.ContainsFunc(roles, p.RoleRequiresMFA), nil
+}
+
+// RequiresEnrollment reports whether the user must enroll a second factor
+// before receiving anything but an enrollment-only token.
+func (p MFAProvider) RequiresEnrollment(ctx context.Context, userID string) (bool, error) {
+	required, err := p.RequiresMFA(ctx, userID)
+	if err != nil || !required {
+		return false, err
+	}
+
+	enabled, err := p.IsEnabled(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return !enabled, nil
+}
+
+// ensureFactorRemovable refuses to remove the last second factor of a user
+// the policy applies to. remaining is the number of factors left afterwards.
+func (p MFAProvider) ensureFactorRemovable(ctx context.Context, userID string, remaining int) error {
+	if remaining > 0 {
+		return nil
+	}
+
+	required, err := p.RequiresMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequired
+	}
+
+	return nil
+}
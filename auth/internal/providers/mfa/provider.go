@@ -0,0 +1,266 @@
+package mfa
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image/png"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+const (
+	MethodTOTP         = "totp"
+	MethodRecoveryCode = "recovery_code"
+	MethodWebAuthn     = "webauthn"
+)
+
+const (
+	totpPeriod = 30
+	totpSkew   = 1
+	qrCodeSize = 256
+)
+
+var totpOpts = totp.ValidateOpts{
+	Period:    totpPeriod,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+type Repository interface {
+	UpsertTOTPSecret(ctx context.Context, userID, secret string) (bool, error)
+	GetTOTP(ctx context.Context, userID string) (models.TOTP, error)
+	EnableTOTP(ctx context.Context, userID string, step int64) (bool, error)
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID string) error
+
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	DeleteRecoveryCodes(ctx context.Context, userID string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+
+	CreateWebAuthnCredential(ctx context.Context, userID string, credentialID []byte, name string, credential []byte) error
+	GetWebAuthnCredentials(ctx context.Context, userID string) ([]models.WebAuthnCredential, error)
+	UpdateWebAuthnCredential(ctx context.Context, credentialID []byte, credential []byte) error
+	DeleteWebAuthnCredential(ctx context.Context, userID, id string) (bool, error)
+	CreateWebAuthnSession(ctx context.Context, userID sql.NullString, ceremony string, data []byte, expiresAt time.Time) (string, error)
+	TakeWebAuthnSession(ctx context.Context, sessionID, ceremony string) (models.WebAuthnSession, error)
+
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+}
+
+type MFAProvider struct {
+	repository    Repository
+	issuer        string
+	webAuthn      *webauthn.WebAuthn
+	requiredRoles []string
+}
+
+func New(repository Repository, issuer string, webAuthn *webauthn.WebAuthn, requiredRoles []string) MFAProvider {
+	return MFAProvider{
+		repository:    repository,
+		issuer:        issuer,
+		webAuthn:      webAuthn,
+		requiredRoles: requiredRoles,
+	}
+}
+
+// TOTP...
+
+// EnrollTOTP creates a new pending TOTP secret for the user. The secret
+// becomes active only after ConfirmTOTP succeeds with a code from it.
+func (p MFAProvider) EnrollTOTP(ctx context.Context, userID, accountName string) (TOTPEnrollment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      p.issuer,
+		AccountName: accountName,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return TOTPEnrollment{}, ErrTOTPGenerate
+	}
+
+	stored, err := p.repository.UpsertTOTPSecret(ctx, userID, key.Secret())
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("failed to store totp secret: %w", err)
+	}
+	if !stored {
+		return TOTPEnrollment{}, ErrTOTPAlreadyEnabled
+	}
+
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("failed to render qr code: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	return TOTPEnrollment{
+		Secret:          key.Secret(),
+		ProvisioningURI: key.URL(),
+		QRCode:          buf.Bytes(),
+	}, nil
+}
+
+// ConfirmTOTP activates the pending secret and returns the initial set of
+// recovery codes.
+func (p MFAProvider) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	secret, err := p.getTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if secret.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	step, ok := matchTOTPStep(secret.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	enabled, err := p.repository.EnableTOTP(ctx, userID, step)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if !enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	return p.GenerateRecoveryCodes(ctx, userID)
+}
+
+// VerifyTOTP checks a code against the user's active secret. Every time step
+// is accepted at most once, so an intercepted code cannot be replayed.
+func (p MFAProvider) VerifyTOTP(ctx context.Context, userID, code string) error {
+	secret, err := p.getTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !secret.Enabled {
+		return ErrTOTPNotEnrolled
+	}
+
+	step, ok := matchTOTPStep(secret.Secret, code, time.Now())
+	if !ok || step <= secret.LastUsedStep {
+		return ErrInvalidCode
+	}
+
+	used, err := p.repository.UseTOTPStep(ctx, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to store totp step: %w", err)
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+func (p MFAProvider) DisableTOTP(ctx context.Context, userID, code string) error {
+	hasPasskeys, err := p.hasPasskeys(ctx, userID)
+	if err != nil {
+		return err
+	}
+	remaining := 0
+	if hasPasskeys {
+		remaining = 1
+	}
+	if err := p.ensureFactorRemovable(ctx, userID, remaining); err != nil {
+		return err
+	}
+
+	if err := p.VerifyTOTP(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := p.repository.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	return p.repository.DeleteTOTP(ctx, userID)
+}
+
+// IsEnabled reports whether the user has to pass a second factor on login.
+func (p MFAProvider) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	methods, err := p.Methods(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return len(methods) > 0, nil
+}
+
+// Methods lists the second factors the user can currently pass.
+func (p MFAProvider) Methods(ctx context.Context, userID string) ([]string, error) {
+	var methods []string
+
+	secret, err := p.repository.GetTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil && secret.Enabled {
+		methods = append(methods, MethodTOTP)
+
+		remaining, err := p.repository.CountRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if remaining > 0 {
+			methods = append(methods, MethodRecoveryCode)
+		}
+	}
+
+	hasPasskeys, err := p.hasPasskeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if hasPasskeys {
+		methods = append(methods, MethodWebAuthn)
+	}
+
+	return methods, nil
+}
+
+func (p MFAProvider) getTOTP(ctx context.Context, userID string) (models.TOTP, error) {
+	secret, err := p.repository.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTP{}, ErrTOTPNotEnrolled
+		}
+		return models.TOTP{}, err
+	}
+
+	return secret, nil
+}
+
+// matchTOTPStep returns the time step the code belongs to, allowing for
+// totpSkew steps of clock drift in either direction.
+func matchTOTPStep(secret, code string, now time.Time) (int64, bool) {
+	if len(code) != int(otp.DigitsSix) {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		step := current + int64(i)
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,96 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	recoveryCodesCount    = 10
+	recoveryCodeLength    = 10
+	recoveryCodeAlphabet  = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeSeparator = "-"
+)
+
+// GenerateRecoveryCodes replaces all recovery codes of the user with a fresh
+// set. Only hashes are stored, so the returned codes are shown exactly once.
+func (p MFAProvider) GenerateRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for range recoveryCodesCount {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, ErrRecoveryCodesGenerate
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := p.repository.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes requires a valid TOTP code before issuing new codes.
+func (p MFAProvider) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := p.VerifyTOTP(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	return p.GenerateRecoveryCodes(ctx, userID)
+}
+
+// UseRecoveryCode consumes a recovery code in place of a TOTP code.
+func (p MFAProvider) UseRecoveryCode(ctx context.Context, userID, code string) error {
+	used, err := p.repository.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+func (p MFAProvider) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	return p.repository.CountRecoveryCodes(ctx, userID)
+}
+
+// newRecoveryCode draws every character uniformly from the alphabet. Its
+// length does not divide 256, so random bytes can not be mapped directly.
+func newRecoveryCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	var code strings.Builder
+	for i := range recoveryCodeLength {
+		if i == recoveryCodeLength/2 {
+			code.WriteString(recoveryCodeSeparator)
+		}
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+
+	return code.String(), nil
+}
+
+// hashRecoveryCode ignores case, whitespace and separators so users can type
+// the code the way it is printed or without the dash.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.TrimSpace(code))
+	normalized = strings.ReplaceAll(normalized, recoveryCodeSeparator, "")
+	normalized = strings.ReplaceAll(normalized, " ", "")
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
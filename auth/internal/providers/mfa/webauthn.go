@@ -0,0 +1,305 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+	ceremonyMFA          = "mfa"
+
+	webAuthnSessionTTL = 5 * time.Minute
+)
+
+// webAuthnUser adapts a user and their stored credentials to webauthn.User.
+// The user ID doubles as the WebAuthn user handle, which lets discoverable
+// logins resolve the account without a username.
+type webAuthnUser struct {
+	id          string
+	name        string
+	displayName string
+	credentials []webauthn.Credential
+}
+
+func (u webAuthnUser) WebAuthnID() []byte                         { return []byte(u.id) }
+func (u webAuthnUser) WebAuthnName() string                       { return u.name }
+func (u webAuthnUser) WebAuthnDisplayName() string                { return u.displayName }
+func (u webAuthnUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+// Registration...
+
+func (p MFAProvider) BeginPasskeyRegistration(ctx context.Context, user PasskeyUser) (WebAuthnChallenge, error) {
+	waUser, err := p.loadWebAuthnUser(ctx, user)
+	if err != nil {
+		return WebAuthnChallenge{}, err
+	}
+
+	creation, session, err := p.webAuthn.BeginRegistration(waUser,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(webauthn.Credentials(waUser.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		return WebAuthnChallenge{}, fmt.Errorf("failed to begin registration: %w", err)
+	}
+
+	return p.saveWebAuthnSession(ctx, user.ID, ceremonyRegistration, session, creation)
+}
+
+func (p MFAProvider) FinishPasskeyRegistration(ctx context.Context, user PasskeyUser, sessionID, name string, response []byte) error {
+	session, err := p.takeWebAuthnSession(ctx, sessionID, ceremonyRegistration, user.ID)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return ErrInvalidPasskey
+	}
+
+	waUser, err := p.loadWebAuthnUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	credential, err := p.webAuthn.CreateCredential(waUser, session, parsed)
+	if err != nil {
+		return ErrInvalidPasskey
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+
+	return p.repository.CreateWebAuthnCredential(ctx, user.ID, credential.ID, name, data)
+}
+
+func (p MFAProvider) ListPasskeys(ctx context.Context, userID string) ([]Passkey, error) {
+	credentials, err := p.repository.GetWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys := make([]Passkey, 0, len(credentials))
+	for _, credential := range credentials {
+		passkeys = append(passkeys, DBWebAuthnCredential2Passkey(credential))
+	}
+
+	return passkeys, nil
+}
+
+func (p MFAProvider) DeletePasskey(ctx context.Context, userID, passkeyID string) error {
+	methods, err := p.Methods(ctx, userID)
+	if err != nil {
+		return err
+	}
+	passkeys, err := p.repository.GetWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return err
+	}
+	remaining := len(passkeys) - 1
+	if slices.Contains(methods, MethodTOTP) {
+		remaining++
+	}
+	if err := p.ensureFactorRemovable(ctx, userID, remaining); err != nil {
+		return err
+	}
+
+	deleted, err := p.repository.DeleteWebAuthnCredential(ctx, userID, passkeyID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPasskeyNotFound
+	}
+
+	return nil
+}
+
+// Passwordless login...
+
+// BeginPasskeyLogin starts a discoverable assertion: the authenticator picks
+// the account, so no login is required up front.
+func (p MFAProvider) BeginPasskeyLogin(ctx context.Context) (WebAuthnChallenge, error) {
+	assertion, session, err := p.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return WebAuthnChallenge{}, fmt.Errorf("failed to begin login: %w", err)
+	}
+
+	return p.saveWebAuthnSession(ctx, "", ceremonyLogin, session, assertion)
+}
+
+// FinishPasskeyLogin returns the ID of the user the passkey belongs to.
+func (p MFAProvider) FinishPasskeyLogin(ctx context.Context, sessionID string, response []byte) (string, error) {
+	session, err := p.takeWebAuthnSession(ctx, sessionID, ceremonyLogin, "")
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return "", ErrInvalidPasskey
+	}
+
+	var waUser webAuthnUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		user, err := p.loadWebAuthnUser(ctx, PasskeyUser{ID: string(userHandle)})
+		waUser = user
+		return user, err
+	}
+
+	credential, err := p.webAuthn.ValidateDiscoverableLogin(handler, session, parsed)
+	if err != nil {
+		return "", ErrInvalidPasskey
+	}
+
+	if err := p.updatePasskey(ctx, credential); err != nil {
+		return "", err
+	}
+
+	return waUser.id, nil
+}
+
+// Second factor...
+
+func (p MFAProvider) BeginPasskeyMFA(ctx context.Context, userID string) (WebAuthnChallenge, error) {
+	waUser, err := p.loadWebAuthnUser(ctx, PasskeyUser{ID: userID})
+	if err != nil {
+		return WebAuthnChallenge{}, err
+	}
+	if len(waUser.credentials) == 0 {
+		return WebAuthnChallenge{}, ErrPasskeyNotFound
+	}
+
+	assertion, session, err := p.webAuthn.BeginLogin(waUser)
+	if err != nil {
+		return WebAuthnChallenge{}, fmt.Errorf("failed to begin login: %w", err)
+	}
+
+	return p.saveWebAuthnSession(ctx, userID, ceremonyMFA, session, assertion)
+}
+
+func (p MFAProvider) FinishPasskeyMFA(ctx context.Context, userID, sessionID string, response []byte) error {
+	session, err := p.takeWebAuthnSession(ctx, sessionID, ceremonyMFA, userID)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return ErrInvalidPasskey
+	}
+
+	waUser, err := p.loadWebAuthnUser(ctx, PasskeyUser{ID: userID})
+	if err != nil {
+		return err
+	}
+
+	credential, err := p.webAuthn.ValidateLogin(waUser, session, parsed)
+	if err != nil {
+		return ErrInvalidPasskey
+	}
+
+	return p.updatePasskey(ctx, credential)
+}
+
+func (p MFAProvider) hasPasskeys(ctx context.Context, userID string) (bool, error) {
+	credentials, err := p.repository.GetWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return len(credentials) > 0, nil
+}
+
+// updatePasskey persists the new signature counter. A counter that did not
+// grow means the credential may have been cloned, so the login is refused.
+func (p MFAProvider) updatePasskey(ctx context.Context, credential *webauthn.Credential) error {
+	if credential.Authenticator.CloneWarning {
+		return ErrInvalidPasskey
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+
+	return p.repository.UpdateWebAuthnCredential(ctx, credential.ID, data)
+}
+
+func (p MFAProvider) loadWebAuthnUser(ctx context.Context, user PasskeyUser) (webAuthnUser, error) {
+	stored, err := p.repository.GetWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return webAuthnUser{}, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, s := range stored {
+		var credential webauthn.Credential
+		if err := json.Unmarshal(s.Credential, &credential); err != nil {
+			return webAuthnUser{}, fmt.Errorf("failed to decode credential: %w", err)
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return webAuthnUser{
+		id:          user.ID,
+		name:        user.Name,
+		displayName: user.DisplayName,
+		credentials: credentials,
+	}, nil
+}
+
+func (p MFAProvider) saveWebAuthnSession(ctx context.Context, userID, ceremony string, session *webauthn.SessionData, options any) (WebAuthnChallenge, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return WebAuthnChallenge{}, err
+	}
+
+	sessionID, err := p.repository.CreateWebAuthnSession(ctx,
+		sql.NullString{String: userID, Valid: userID != ""}, ceremony, data, time.Now().Add(webAuthnSessionTTL))
+	if err != nil {
+		return WebAuthnChallenge{}, fmt.Errorf("failed to store webauthn session: %w", err)
+	}
+
+	return WebAuthnChallenge{
+		SessionID: sessionID,
+		Options:   options,
+	}, nil
+}
+
+// takeWebAuthnSession loads and deletes a ceremony session, so every
+// challenge can be answered once. Sessions bound to a user can only be
+// finished by that user.
+func (p MFAProvider) takeWebAuthnSession(ctx context.Context, sessionID, ceremony, userID string) (webauthn.SessionData, error) {
+	stored, err := p.repository.TakeWebAuthnSession(ctx, sessionID, ceremony)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return webauthn.SessionData{}, ErrWebAuthnSession
+		}
+		return webauthn.SessionData{}, err
+	}
+
+	if stored.UserID.String != userID {
+		return webauthn.SessionData{}, ErrWebAuthnSession
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(stored.Data, &session); err != nil {
+		return webauthn.SessionData{}, fmt.Errorf("failed to decode webauthn session: %w", err)
+	}
+
+	return session, nil
+}
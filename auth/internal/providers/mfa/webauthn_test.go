@@ -0,0 +1,453 @@
+package mfa
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+const (
+	testRPID   = "auth.example.test"
+	testOrigin = "https://auth.example.test"
+)
+
+var testUser = PasskeyUser{ID: "6f1c2a9e-3b7d-4c55-9a10-2f8e4b6d7c01", Name: "ada", DisplayName: "Ada Lovelace"}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	ctx := context.Background()
+	p, repo := newTestProvider(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+
+	registerPasskey(t, p, authenticator)
+	if len(repo.credentials) != 1 {
+		t.Fatalf("stored %d credentials, want 1", len(repo.credentials))
+	}
+
+	challenge, err := p.BeginPasskeyLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginPasskeyLogin: %v", err)
+	}
+	response := authenticator.assert(t, assertionChallenge(t, challenge))
+
+	userID, err := p.FinishPasskeyLogin(ctx, challenge.SessionID, response)
+	if err != nil {
+		t.Fatalf("FinishPasskeyLogin: %v", err)
+	}
+	if userID != testUser.ID {
+		t.Fatalf("FinishPasskeyLogin returned user %q, want %q", userID, testUser.ID)
+	}
+
+	var stored webauthn.Credential
+	if err := json.Unmarshal(repo.credentials[0].Credential, &stored); err != nil {
+		t.Fatalf("decode stored credential: %v", err)
+	}
+	if stored.Authenticator.SignCount != authenticator.signCount {
+		t.Fatalf("stored sign count %d, want %d", stored.Authenticator.SignCount, authenticator.signCount)
+	}
+
+	// The session is consumed by the first answer.
+	if _, err := p.FinishPasskeyLogin(ctx, challenge.SessionID, response); !errors.Is(err, ErrWebAuthnSession) {
+		t.Fatalf("replayed assertion: got %v, want %v", err, ErrWebAuthnSession)
+	}
+}
+
+func TestPasskeyMFA(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestProvider(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	registerPasskey(t, p, authenticator)
+
+	methods, err := p.Methods(ctx, testUser.ID)
+	if err != nil {
+		t.Fatalf("Methods: %v", err)
+	}
+	if len(methods) != 1 || methods[0] != MethodWebAuthn {
+		t.Fatalf("Methods = %v, want [%s]", methods, MethodWebAuthn)
+	}
+
+	challenge, err := p.BeginPasskeyMFA(ctx, testUser.ID)
+	if err != nil {
+		t.Fatalf("BeginPasskeyMFA: %v", err)
+	}
+	response := authenticator.assert(t, assertionChallenge(t, challenge))
+
+	if err := p.FinishPasskeyMFA(ctx, "someone-else", challenge.SessionID, response); !errors.Is(err, ErrWebAuthnSession) {
+		t.Fatalf("FinishPasskeyMFA for another user: got %v, want %v", err, ErrWebAuthnSession)
+	}
+
+	challenge, err = p.BeginPasskeyMFA(ctx, testUser.ID)
+	if err != nil {
+		t.Fatalf("BeginPasskeyMFA: %v", err)
+	}
+	response = authenticator.assert(t, assertionChallenge(t, challenge))
+
+	if err := p.FinishPasskeyMFA(ctx, testUser.ID, challenge.SessionID, response); err != nil {
+		t.Fatalf("FinishPasskeyMFA: %v", err)
+	}
+}
+
+func TestPasskeyLoginRejectsSignCountRegression(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestProvider(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	registerPasskey(t, p, authenticator)
+
+	authenticator.signCount = 10
+	challenge, err := p.BeginPasskeyLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginPasskeyLogin: %v", err)
+	}
+	if _, err := p.FinishPasskeyLogin(ctx, challenge.SessionID, authenticator.assert(t, assertionChallenge(t, challenge))); err != nil {
+		t.Fatalf("FinishPasskeyLogin: %v", err)
+	}
+
+	// A clone of the key still counts from an older state.
+	authenticator.signCount = 4
+	challenge, err = p.BeginPasskeyLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginPasskeyLogin: %v", err)
+	}
+	_, err = p.FinishPasskeyLogin(ctx, challenge.SessionID, authenticator.assert(t, assertionChallenge(t, challenge)))
+	if !errors.Is(err, ErrInvalidPasskey) {
+		t.Fatalf("FinishPasskeyLogin with a lower sign count: got %v, want %v", err, ErrInvalidPasskey)
+	}
+}
+
+func TestPasskeyRejectsWrongOrigin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("registration", func(t *testing.T) {
+		p, repo := newTestProvider(t)
+		phished := newSoftAuthenticator(t, "https://auth.example.test.evil.example")
+
+		challenge, err := p.BeginPasskeyRegistration(ctx, testUser)
+		if err != nil {
+			t.Fatalf("BeginPasskeyRegistration: %v", err)
+		}
+		response := phished.register(t, creationChallenge(t, challenge))
+
+		err = p.FinishPasskeyRegistration(ctx, testUser, challenge.SessionID, "phished", response)
+		if !errors.Is(err, ErrInvalidPasskey) {
+			t.Fatalf("FinishPasskeyRegistration: got %v, want %v", err, ErrInvalidPasskey)
+		}
+		if len(repo.credentials) != 0 {
+			t.Fatalf("stored %d credentials, want none", len(repo.credentials))
+		}
+	})
+
+	t.Run("login", func(t *testing.T) {
+		p, _ := newTestProvider(t)
+		authenticator := newSoftAuthenticator(t, testOrigin)
+		registerPasskey(t, p, authenticator)
+
+		authenticator.origin = "https://auth.example.test.evil.example"
+		challenge, err := p.BeginPasskeyLogin(ctx)
+		if err != nil {
+			t.Fatalf("BeginPasskeyLogin: %v", err)
+		}
+
+		_, err = p.FinishPasskeyLogin(ctx, challenge.SessionID, authenticator.assert(t, assertionChallenge(t, challenge)))
+		if !errors.Is(err, ErrInvalidPasskey) {
+			t.Fatalf("FinishPasskeyLogin: got %v, want %v", err, ErrInvalidPasskey)
+		}
+	})
+}
+
+func newTestProvider(t *testing.T) (MFAProvider, *memoryRepository) {
+	t.Helper()
+
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          testRPID,
+		RPDisplayName: "Auth",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatalf("webauthn.New: %v", err)
+	}
+
+	repo := &memoryRepository{sessions: map[string]models.WebAuthnSession{}}
+	return New(repo, "Auth", webAuthn, nil), repo
+}
+
+func registerPasskey(t *testing.T, p MFAProvider, authenticator *softAuthenticator) {
+	t.Helper()
+	ctx := context.Background()
+
+	challenge, err := p.BeginPasskeyRegistration(ctx, testUser)
+	if err != nil {
+		t.Fatalf("BeginPasskeyRegistration: %v", err)
+	}
+
+	response := authenticator.register(t, creationChallenge(t, challenge))
+	if err := p.FinishPasskeyRegistration(ctx, testUser, challenge.SessionID, "laptop", response); err != nil {
+		t.Fatalf("FinishPasskeyRegistration: %v", err)
+	}
+}
+
+func creationChallenge(t *testing.T, challenge WebAuthnChallenge) string {
+	t.Helper()
+
+	creation, ok := challenge.Options.(*protocol.CredentialCreation)
+	if !ok {
+		t.Fatalf("registration options are %T", challenge.Options)
+	}
+	return creation.Response.Challenge.String()
+}
+
+func assertionChallenge(t *testing.T, challenge WebAuthnChallenge) string {
+	t.Helper()
+
+	assertion, ok := challenge.Options.(*protocol.CredentialAssertion)
+	if !ok {
+		t.Fatalf("login options are %T", challenge.Options)
+	}
+	return assertion.Response.Challenge.String()
+}
+
+// softAuthenticator is a resident P-256 key answering ceremonies the way a
+// platform authenticator with user verification would.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	origin       string
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, origin string) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	credentialID := make([]byte, 32)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("generate credential ID: %v", err)
+	}
+
+	return &softAuthenticator{key: key, credentialID: credentialID, origin: origin}
+}
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+func (a *softAuthenticator) register(t *testing.T, challenge string) []byte {
+	t.Helper()
+
+	publicKey, err := webauthncbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("encode public key: %v", err)
+	}
+
+	authData := a.authenticatorData(flagUserPresent | flagUserVerified | flagAttestedData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatalf("encode attestation object: %v", err)
+	}
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    encode(a.clientData(t, "webauthn.create", challenge)),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+func (a *softAuthenticator) assert(t *testing.T, challenge string) []byte {
+	t.Helper()
+
+	clientData := a.clientData(t, "webauthn.get", challenge)
+	authData := a.authenticatorData(flagUserPresent | flagUserVerified)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode([]byte(testUser.ID)),
+	})
+}
+
+func (a *softAuthenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony, challenge string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    a.origin,
+	})
+	if err != nil {
+		t.Fatalf("encode client data: %v", err)
+	}
+	return data
+}
+
+func (a *softAuthenticator) response(t *testing.T, response map[string]string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatalf("encode credential: %v", err)
+	}
+	return data
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// memoryRepository keeps passkeys and ceremony sessions in memory. The user
+// has no TOTP or recovery codes.
+type memoryRepository struct {
+	credentials []models.WebAuthnCredential
+	sessions    map[string]models.WebAuthnSession
+	nextID      int
+}
+
+func (r *memoryRepository) UpsertTOTPSecret(ctx context.Context, userID, secret string) (bool, error) {
+	return false, errors.New("not implemented")
+}
+
+func (r *memoryRepository) GetTOTP(ctx context.Context, userID string) (models.TOTP, error) {
+	return models.TOTP{}, sql.ErrNoRows
+}
+
+func (r *memoryRepository) EnableTOTP(ctx context.Context, userID string, step int64) (bool, error) {
+	return false, errors.New("not implemented")
+}
+
+func (r *memoryRepository) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	return false, errors.New("not implemented")
+}
+
+func (r *memoryRepository) DeleteTOTP(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (r *memoryRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	return errors.New("not implemented")
+}
+
+func (r *memoryRepository) DeleteRecoveryCodes(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (r *memoryRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	return false, nil
+}
+
+func (r *memoryRepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	return 0, nil
+}
+
+func (r *memoryRepository) CreateWebAuthnCredential(ctx context.Context, userID string, credentialID []byte, name string, credential []byte) error {
+	r.nextID++
+	r.credentials = append(r.credentials, models.WebAuthnCredential{
+		ID:           strconv.Itoa(r.nextID),
+		UserID:       userID,
+		CredentialID: credentialID,
+		Name:         name,
+		Credential:   credential,
+		CreatedAt:    time.Now().Format(time.RFC3339),
+	})
+	return nil
+}
+
+func (r *memoryRepository) GetWebAuthnCredentials(ctx context.Context, userID string) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	for _, credential := range r.credentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, credential)
+		}
+	}
+	return credentials, nil
+}
+
+func (r *memoryRepository) UpdateWebAuthnCredential(ctx context.Context, credentialID []byte, credential []byte) error {
+	for i := range r.credentials {
+		if bytes.Equal(r.credentials[i].CredentialID, credentialID) {
+			r.credentials[i].Credential = credential
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *memoryRepository) DeleteWebAuthnCredential(ctx context.Context, userID, id string) (bool, error) {
+	for i, credential := range r.credentials {
+		if credential.UserID == userID && credential.ID == id {
+			r.credentials = append(r.credentials[:i], r.credentials[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepository) CreateWebAuthnSession(ctx context.Context, userID sql.NullString, ceremony string, data []byte, expiresAt time.Time) (string, error) {
+	r.nextID++
+	sessionID := strconv.Itoa(r.nextID)
+	r.sessions[sessionID] = models.WebAuthnSession{ID: sessionID, UserID: userID, Ceremony: ceremony, Data: data}
+	return sessionID, nil
+}
+
+func (r *memoryRepository) TakeWebAuthnSession(ctx context.Context, sessionID, ceremony string) (models.WebAuthnSession, error) {
+	session, ok := r.sessions[sessionID]
+	if !ok || session.Ceremony != ceremony {
+		return models.WebAuthnSession{}, sql.ErrNoRows
+	}
+	delete(r.sessions, sessionID)
+	return session, nil
+}
+
+func (r *memoryRepository) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	return nil, nil
+}
@@ -9,4 +9,6 @@ var (
 	ErrTokenParse      = errors.New("token parse error")
 	ErrInvalidRole     = errors.New("invalid role")
 	ErrInvalidKey      = errors.New("invalid key")
+	ErrSessionRevoked  = errors.New("session revoked")
+	ErrImpersonation   = errors.New("not allowed with an impersonation token")
 )
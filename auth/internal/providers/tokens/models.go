@@ -1,6 +1,14 @@
 package tokens
 
+import "time"
+
 type Tokens struct {
 	AccessToken  string
 	RefreshToken string
 }
+
+type ImpersonationToken struct {
+	AccessToken string
+	ID          string
+	ExpiresAt   time.Time
+}
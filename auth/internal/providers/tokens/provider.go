@@ -1,14 +1,21 @@
 package tokens
 
 import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
 )
 
-type Repository interface{}
+type Repository interface {
+	GetSessionState(ctx context.Context, userID string) (models.SessionState, error)
+}
 
 type TokensProvider struct {
 	repository Repository
@@ -21,10 +28,24 @@ func New(repository Repository) TokensProvider {
 }
 
 var (
-	accessPrivateKey  string
-	accessTokenTTL    = 15 * time.Minute
-	refreshPrivateKey string
-	refreshTokenTTL   = 7 * 24 * time.Hour
+	accessPrivateKey   string
+	accessTokenTTL     = 15 * time.Minute
+	refreshPrivateKey  string
+	refreshTokenTTL    = 7 * 24 * time.Hour
+	mfaTokenTTL        = 5 * time.Minute
+	enrollmentTokenTTL = 15 * time.Minute
+	// impersonationTokenTTL is short on purpose, impersonation tokens can
+	// not be refreshed.
+	impersonationTokenTTL = 10 * time.Minute
+	activationKeyTTL      = 30 * 24 * time.Hour
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeMFA     = "mfa"
+	// TokenTypeEnrollment only grants access to second factor enrollment.
+	TokenTypeEnrollment = "mfa_enrollment"
 )
 
 func InitJWT(accessKey, refreshKey string) error {
@@ -34,57 +55,165 @@ func InitJWT(accessKey, refreshKey string) error {
 }
 
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	TokenType string `json:"token_type"`
+	// Roles are the user's roles when an access token was issued. They are
+	// informational for clients, authorization always checks the database.
+	Roles []string `json:"roles,omitempty"`
+	// Act identifies the admin acting as the user in impersonation tokens,
+	// see RFC 8693 section 4.1.
+	Act *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
 // Auth tokens...
 
-func (p *TokensProvider) GenerateAccessToken(userID string) (string, error) {
+func (p *TokensProvider) GenerateAccessToken(userID string, roles []string) (string, error) {
+	return signClaims(&Claims{
+		UserID:           userID,
+		TokenType:        TokenTypeAccess,
+		Roles:            roles,
+		RegisteredClaims: registeredClaims(accessTokenTTL),
+	}, accessPrivateKey)
+}
+
+func (p *TokensProvider) GenerateRefreshToken(userID string) (string, error) {
+	return generateToken(userID, TokenTypeRefresh, refreshTokenTTL, refreshPrivateKey)
+}
+
+func (p *TokensProvider) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return validateToken(tokenString, TokenTypeAccess, accessPrivateKey)
+}
+
+func (p *TokensProvider) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return validateToken(tokenString, TokenTypeRefresh, refreshPrivateKey)
+}
+
+// CheckSession rejects tokens issued before the user's sessions were
+// revoked, e.g. by an email change, and all tokens of deactivated or
+// deleted accounts.
+func (p *TokensProvider) CheckSession(ctx context.Context, claims *Claims) error {
+	state, err := p.repository.GetSessionState(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+
+	if !state.Active {
+		return ErrSessionRevoked
+	}
+
+	// iat only has whole seconds, a token issued right after the revocation
+	// must not look older than it.
+	if state.RevokedAt.Valid && (claims.IssuedAt == nil || claims.IssuedAt.Time.Before(state.RevokedAt.Time.Truncate(time.Second))) {
+		return ErrSessionRevoked
+	}
+
+	if claims.Act != nil {
+		return p.CheckSession(ctx, &Claims{UserID: claims.Act.Subject, RegisteredClaims: claims.RegisteredClaims})
+	}
+
+	return nil
+}
+
+// Impersonation tokens...
+
+// GenerateImpersonationToken issues an access token for the user on behalf of
+// the actor. It comes without a refresh token and is also bound to the
+// actor's session, see CheckSession.
+func (p *TokensProvider) GenerateImpersonationToken(userID string, roles []string, actorID string) (ImpersonationToken, error) {
+	tokenID := make([]byte, 16)
+	if _, err := rand.Read(tokenID); err != nil {
+		return ImpersonationToken{}, err
+	}
+
 	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
-		},
+		UserID:           userID,
+		TokenType:        TokenTypeAccess,
+		Roles:            roles,
+		Act:              &Actor{Subject: actorID},
+		RegisteredClaims: registeredClaims(impersonationTokenTTL),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString([]byte(accessPrivateKey))
+	claims.ID = hex.EncodeToString(tokenID)
+
+	token, err := signClaims(claims, accessPrivateKey)
 	if err != nil {
-		return "", err
+		return ImpersonationToken{}, fmt.Errorf("%w: %w", ErrAccessGenerate, err)
 	}
-	return accessToken, nil
+
+	return ImpersonationToken{
+		AccessToken: token,
+		ID:          claims.ID,
+		ExpiresAt:   claims.ExpiresAt.Time,
+	}, nil
 }
 
-func (p *TokensProvider) GenerateRefreshToken(userID string) (string, error) {
-	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(refreshTokenTTL)),
-		},
+// MFA challenge tokens...
+
+// GenerateMFAToken issues a short-lived token proving that the password step
+// of the login succeeded. It can only be exchanged for a token pair after
+// the second factor is checked.
+func (p *TokensProvider) GenerateMFAToken(userID string) (string, error) {
+	return generateToken(userID, TokenTypeMFA, mfaTokenTTL, accessPrivateKey)
+}
+
+func (p *TokensProvider) ValidateMFAToken(tokenString string) (*Claims, error) {
+	return validateToken(tokenString, TokenTypeMFA, accessPrivateKey)
+}
+
+// GenerateEnrollmentToken issues a restricted token for users whose role
+// requires MFA but who have not enrolled a second factor yet.
+func (p *TokensProvider) GenerateEnrollmentToken(userID string) (string, error) {
+	return generateToken(userID, TokenTypeEnrollment, enrollmentTokenTTL, accessPrivateKey)
+}
+
+func (p *TokensProvider) ValidateEnrollmentToken(tokenString string) (*Claims, error) {
+	return validateToken(tokenString, TokenTypeEnrollment, accessPrivateKey)
+}
+
+func generateToken(userID, tokenType string, ttl time.Duration, key string) (string, error) {
+	return signClaims(&Claims{
+		UserID:           userID,
+		TokenType:        tokenType,
+		RegisteredClaims: registeredClaims(ttl),
+	}, key)
+}
+
+func registeredClaims(ttl time.Duration) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
+}
+
+func signClaims(claims *Claims, key string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	refreshToken, err := token.SignedString([]byte(refreshPrivateKey))
+	signed, err := token.SignedString([]byte(key))
 	if err != nil {
 		return "", err
 	}
-	return refreshToken, nil
+	return signed, nil
 }
 
-func (p *TokensProvider) ValidateRefreshToken(tokenString string) (*Claims, error) {
+func validateToken(tokenString, tokenType, key string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(refreshPrivateKey), nil
+		return []byte(key), nil
 	})
 
-	log.Default().Println("[REFRESH TOKEN]:", token)
-
 	if err != nil {
 		return nil, fmt.Errorf("token parsing failed: %w", err)
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.TokenType == tokenType {
 		return claims, nil
 	}
 
@@ -100,7 +229,14 @@ func (p *TokensProvider) GenerateRoleKey(
 	enrollmentYear int,
 	degree string,
 ) (string, error) {
+	keyID := make([]byte, 16)
+	if _, err := rand.Read(keyID); err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{
+		"jti":           hex.EncodeToString(keyID),
+		"exp":           time.Now().Add(activationKeyTTL).Unix(),
 		"role":          role,
 		"university_id": universityID,
 	}
@@ -119,19 +255,24 @@ func (p *TokensProvider) GenerateRoleKey(
 	return token.SignedString([]byte(refreshPrivateKey))
 }
 
+// ValidateRoleKey only accepts keys that expire and have a jti, the ID they
+// are redeemed under. Keys issued before both were added are refused.
 func (p *TokensProvider) ValidateRoleKey(key string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(key, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(refreshPrivateKey), nil
-	})
+	}, jwt.WithExpirationRequired())
 
 	if err != nil {
 		return nil, fmt.Errorf("key parsing failed: %w", err)
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		if keyID, _ := claims["jti"].(string); keyID == "" {
+			return nil, ErrInvalidKey
+		}
 		return claims, nil
 	}
 
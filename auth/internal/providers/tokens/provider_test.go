@@ -0,0 +1,72 @@
+package tokens
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+const testUserID = "6f1c2a9e-3b7d-4c55-9a10-2f8e4b6d7c01"
+
+type sessionRepository struct {
+	state models.SessionState
+}
+
+func (r *sessionRepository) GetSessionState(ctx context.Context, userID string) (models.SessionState, error) {
+	return r.state, nil
+}
+
+// revoke stores the revocation with the sub-second precision of now() in
+// Postgres.
+func (r *sessionRepository) revoke(at time.Time) {
+	r.state.RevokedAt = sql.NullTime{Time: at, Valid: true}
+}
+
+func TestCheckSessionAcceptsLoginRightAfterRevocation(t *testing.T) {
+	if err := InitJWT("test-access-key", "test-refresh-key"); err != nil {
+		t.Fatalf("InitJWT: %v", err)
+	}
+	repo := &sessionRepository{state: models.SessionState{Active: true}}
+	p := New(repo)
+
+	// Revoke late in a second, so that the new token is issued within the
+	// same second.
+	now := time.Now()
+	repo.revoke(now.Truncate(time.Second).Add(999 * time.Millisecond))
+
+	token, err := p.GenerateAccessToken(testUserID, nil)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	claims, err := p.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	claims.IssuedAt = jwt.NewNumericDate(now)
+
+	if err := p.CheckSession(context.Background(), claims); err != nil {
+		t.Fatalf("CheckSession for a token issued after the revocation: %v", err)
+	}
+}
+
+func TestCheckSessionRejectsTokensIssuedBeforeRevocation(t *testing.T) {
+	repo := &sessionRepository{state: models.SessionState{Active: true}}
+	p := New(repo)
+
+	now := time.Now()
+	repo.revoke(now)
+	claims := &Claims{
+		UserID:           testUserID,
+		TokenType:        TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(now.Add(-time.Second))},
+	}
+
+	if err := p.CheckSession(context.Background(), claims); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("CheckSession: got %v, want %v", err, ErrSessionRevoked)
+	}
+}
@@ -0,0 +1,118 @@
+package facade
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/storage"
+)
+
+// testDatabaseURLEnv points the tests at a disposable Postgres database, the
+// migrations are applied to it. Without it the tests are skipped.
+const testDatabaseURLEnv = "AUTH_TEST_DATABASE_URL"
+
+func TestEraseUserScrubsConflictsAndRoleRequests(t *testing.T) {
+	ctx := context.Background()
+	f, db := newTestFacade(t)
+
+	suffix := time.Now().Format("150405.000000")
+	keptID := createTestUser(t, f, "kept-"+suffix)
+	userID := createTestUser(t, f, "erased-"+suffix)
+
+	var conflictID string
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO identity_conflicts (user_id, field, value, kept_user_id)
+		VALUES ($1, 'username', $2, $3)
+		RETURNING id`, userID, "Kept-"+suffix, keptID).Scan(&conflictID)
+	if err != nil {
+		t.Fatalf("seed identity conflict: %v", err)
+	}
+
+	request, err := f.CreateRoleRequest(ctx, models.RoleRequest{
+		UserID:       userID,
+		Role:         "student",
+		UniversityID: testUniversityID,
+		Comment:      "I am Jane Doe, student no. 12345",
+	})
+	if err != nil {
+		t.Fatalf("CreateRoleRequest: %v", err)
+	}
+	_, err = f.RejectRoleRequest(ctx, models.RoleReview{
+		RequestID:  request.ID,
+		ReviewedBy: keptID,
+		Comment:    "Jane, your student no. does not match",
+	})
+	if err != nil {
+		t.Fatalf("RejectRoleRequest: %v", err)
+	}
+
+	if _, err := f.SoftDeleteUser(ctx, userID); err != nil {
+		t.Fatalf("SoftDeleteUser: %v", err)
+	}
+	erased, err := f.EraseUser(ctx, userID)
+	if err != nil || !erased {
+		t.Fatalf("EraseUser: got %v, %v, want true, nil", erased, err)
+	}
+
+	var conflicts int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM identity_conflicts WHERE id = $1`, conflictID).Scan(&conflicts); err != nil {
+		t.Fatalf("count identity conflicts: %v", err)
+	}
+	if conflicts != 0 {
+		t.Fatalf("identity conflicts: got %d, want 0", conflicts)
+	}
+
+	requests, err := f.GetUserRoleRequests(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserRoleRequests: %v", err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("role requests: got %d, want 1", len(requests))
+	}
+	if requests[0].Comment != "" || requests[0].ReviewComment.Valid {
+		t.Fatalf("role request comments: got %q, %v, want empty", requests[0].Comment, requests[0].ReviewComment)
+	}
+	if requests[0].Status != models.RoleRequestRejected {
+		t.Fatalf("role request status: got %q, want %q", requests[0].Status, models.RoleRequestRejected)
+	}
+}
+
+func newTestFacade(t *testing.T) (Facade, *sql.DB) {
+	t.Helper()
+	dbURL := os.Getenv(testDatabaseURLEnv)
+	if dbURL == "" {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+
+	s, err := storage.New(dbURL, "file://../../../migrations")
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return New(s), db
+}
+
+func createTestUser(t *testing.T, f Facade, username string) string {
+	t.Helper()
+	userID, _, err := f.RegisterUser(context.Background(), models.RegisterUserData{
+		Username:     username,
+		Email:        fmt.Sprintf("%s@example.com", username),
+		PasswordHash: "!",
+		FirstName:    "Jane",
+		LastName:     "Doe",
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("RegisterUser %s: %v", username, err)
+	}
+	return userID
+}
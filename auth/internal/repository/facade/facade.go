@@ -2,6 +2,10 @@ package facade
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
 	"github.com/vladlim/auth-service-practice/auth/internal/repository/storage"
@@ -39,6 +43,10 @@ func (f Facade) FindUserByID(ctx context.Context, userID string) (bool, error) {
 	return f.storage.FindUserByID(ctx, userID)
 }
 
+func (f Facade) GetPasswordHash(ctx context.Context, userID string) (string, error) {
+	return f.storage.GetPasswordHash(ctx, userID)
+}
+
 // User Info...
 
 func (f Facade) GetUserByID(ctx context.Context, userID string) (models.User, error) {
@@ -69,58 +77,733 @@ func (f Facade) GetUserRoles(ctx context.Context, userID string) ([]string, erro
 	return f.storage.GetUserRoles(ctx, userID)
 }
 
-// Keys...
+// UpdateProfile applies the user and teacher profile changes atomically.
+// It reports false when the user or, for a degree change, the teacher
+// profile does not exist.
+func (f Facade) UpdateProfile(ctx context.Context, userID string, update models.ProfileUpdate) (bool, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	updated, err := tx.UpdateUser(ctx, userID, update.FirstName, update.LastName)
+	if err != nil || !updated {
+		return false, err
+	}
+
+	if update.Degree != nil {
+		updated, err := tx.UpdateTeacher(ctx, userID, update.Degree)
+		if err != nil || !updated {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// Email change...
+
+func (f Facade) CreateEmailChangeRequest(ctx context.Context, userID, oldEmail, newEmail, confirmHash, cancelHash string, expiresAt time.Time) error {
+	return f.storage.CreateEmailChangeRequest(ctx, userID, oldEmail, newEmail, confirmHash, cancelHash, expiresAt)
+}
+
+func (f Facade) CancelPendingEmailChanges(ctx context.Context, userID string) error {
+	return f.storage.CancelPendingEmailChanges(ctx, userID)
+}
+
+func (f Facade) CancelEmailChange(ctx context.Context, cancelHash string) (string, error) {
+	return f.storage.CancelEmailChange(ctx, cancelHash)
+}
+
+// ConfirmEmailChange consumes the request, swaps the email and revokes all
+// sessions of the user in one transaction. It reports false, leaving the
+// request pending, when the new address was taken in the meantime.
+func (f Facade) ConfirmEmailChange(ctx context.Context, confirmHash string) (models.EmailChange, bool, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return models.EmailChange{}, false, err
+	}
+	defer tx.Rollback()
+
+	change, err := tx.ConfirmEmailChange(ctx, confirmHash)
+	if err != nil {
+		return models.EmailChange{}, false, err
+	}
+
+	if _, _, err := tx.FindUserByEmail(ctx, change.NewEmail); err == nil {
+		return change, false, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return change, false, err
+	}
+
+	updated, err := tx.UpdateUserEmail(ctx, change.UserID, change.NewEmail)
+	if err != nil || !updated {
+		return change, false, err
+	}
+
+	if err := tx.RevokeSessions(ctx, change.UserID); err != nil {
+		return change, false, err
+	}
+
+	return change, true, tx.Commit()
+}
+
+// Username history...
+
+func (f Facade) IsUsernameReserved(ctx context.Context, username, userID string) (bool, error) {
+	return f.storage.IsUsernameReserved(ctx, username, userID)
+}
+
+func (f Facade) GetUsernameHistory(ctx context.Context, userID string) ([]models.UsernameHistory, error) {
+	return f.storage.GetUsernameHistory(ctx, userID)
+}
+
+func (f Facade) GetIdentityConflicts(ctx context.Context) ([]models.IdentityConflict, error) {
+	return f.storage.GetIdentityConflicts(ctx)
+}
+
+func (f Facade) GetIdentityConflict(ctx context.Context, conflictID string) (models.IdentityConflict, error) {
+	return f.storage.GetIdentityConflict(ctx, conflictID)
+}
+
+// ResolveIdentityConflict gives the user of an open conflict a new username
+// or email, which also sets its canonical form. The colliding value was never
+// usable, so it is not kept in the username history. It reports false when
+// the new value is taken or reserved by someone else.
+func (f Facade) ResolveIdentityConflict(ctx context.Context, conflictID, value string) (models.IdentityConflict, bool, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return models.IdentityConflict{}, false, err
+	}
+	defer tx.Rollback()
+
+	conflict, err := tx.LockIdentityConflict(ctx, conflictID)
+	if err != nil {
+		return models.IdentityConflict{}, false, err
+	}
+
+	var updated bool
+	switch conflict.Field {
+	case models.IdentityFieldUsername:
+		if reserved, err := tx.IsUsernameReserved(ctx, value, conflict.UserID); err != nil || reserved {
+			return conflict, false, err
+		}
+		updated, err = tx.UpdateUsername(ctx, conflict.UserID, value)
+	case models.IdentityFieldEmail:
+		updated, err = tx.UpdateUserEmail(ctx, conflict.UserID, value)
+	default:
+		return conflict, false, fmt.Errorf("unknown identity field %q", conflict.Field)
+	}
+	if err != nil || !updated {
+		return conflict, false, err
+	}
+
+	return conflict, true, tx.Commit()
+}
+
+// ChangeUsername renames the user and keeps the old name in the history,
+// reserved until reservedUntil. It reports false when the new name is taken
+// or reserved by someone else.
+func (f Facade) ChangeUsername(ctx context.Context, userID, username string, reservedUntil time.Time) (bool, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	oldUsername, err := tx.LockUsername(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if reserved, err := tx.IsUsernameReserved(ctx, username, userID); err != nil || reserved {
+		return false, err
+	}
+
+	updated, err := tx.UpdateUsername(ctx, userID, username)
+	if err != nil || !updated {
+		return false, err
+	}
+
+	if err := tx.CreateUsernameHistory(ctx, userID, oldUsername, reservedUntil); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Sessions...
+
+func (f Facade) RevokeSessions(ctx context.Context, userID string) error {
+	return f.storage.RevokeSessions(ctx, userID)
+}
+
+func (f Facade) GetSessionState(ctx context.Context, userID string) (models.SessionState, error) {
+	return f.storage.GetSessionState(ctx, userID)
+}
+
+// Account status...
+
+func (f Facade) IsUserActive(ctx context.Context, userID string) (bool, error) {
+	return f.storage.IsUserActive(ctx, userID)
+}
 
-func (f Facade) CreateStudent(ctx context.Context, userID, groupID, universityID string, enrollmentYear int) error {
-	return f.storage.CreateStudent(ctx, userID, groupID, universityID, enrollmentYear)
+func (f Facade) DeactivateUser(ctx context.Context, userID string) (bool, error) {
+	return f.storage.DeactivateUser(ctx, userID)
 }
 
-func (f Facade) CreateTeacher(ctx context.Context, userID, universityID, degree string) error {
-	return f.storage.CreateTeacher(ctx, userID, universityID, degree)
+func (f Facade) SoftDeleteUser(ctx context.Context, userID string) (bool, error) {
+	return f.storage.SoftDeleteUser(ctx, userID)
 }
 
-func (f Facade) AddUserRole(ctx context.Context, userID, role string) error {
-	return f.storage.AddUserRole(ctx, userID, role)
+func (f Facade) ReactivateUser(ctx context.Context, userID string) (bool, error) {
+	return f.storage.ReactivateUser(ctx, userID)
 }
 
+func (f Facade) GetUsersToErase(ctx context.Context, deletedBefore time.Time, limit int) ([]string, error) {
+	return f.storage.GetUsersToErase(ctx, deletedBefore, limit)
+}
+
+// EraseUser anonymizes a soft-deleted user. The users, students and teachers
+// rows stay as stubs, everything else tied to the person is removed.
+func (f Facade) EraseUser(ctx context.Context, userID string) (bool, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	erased, err := tx.EraseUser(ctx, userID)
+	if err != nil || !erased {
+		return false, err
+	}
+
+	if err := tx.EraseTeacher(ctx, userID); err != nil {
+		return false, err
+	}
+
+	if err := tx.DeleteUserPersonalData(ctx, userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Audit...
+
+func (f Facade) CreateAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	return f.storage.CreateAuditEvent(ctx, event)
+}
+
+func (f Facade) GetAuditEventsByUser(ctx context.Context, userID string) ([]models.AuditEvent, error) {
+	return f.storage.GetAuditEventsByUser(ctx, userID)
+}
+
+func (f Facade) CreateKeyIssuance(ctx context.Context, issuance models.KeyIssuance) error {
+	return f.storage.CreateKeyIssuance(ctx, issuance)
+}
+
+// CreateLimitedKeyIssuance records the key only while fewer than limit keys
+// were issued by the same issuer since since, and reports false otherwise.
+// The issuer is locked for the transaction, so that concurrent requests can
+// not both take the last key of the quota.
+func (f Facade) CreateLimitedKeyIssuance(ctx context.Context, issuance models.KeyIssuance, limit int, since time.Time) (bool, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.LockKeyIssuer(ctx, issuance.IssuedBy.String); err != nil {
+		return false, err
+	}
+
+	issued, err := tx.CountKeyIssuances(ctx, issuance.IssuedBy.String, since)
+	if err != nil || issued >= limit {
+		return false, err
+	}
+
+	if err := tx.CreateKeyIssuance(ctx, issuance); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (f Facade) GetKeyIssuances(ctx context.Context, issuedBy string) ([]models.KeyIssuance, error) {
+	return f.storage.GetKeyIssuances(ctx, issuedBy)
+}
+
+func (f Facade) GetKeyRedemptions(ctx context.Context, userID string) ([]models.KeyRedemption, error) {
+	return f.storage.GetKeyRedemptions(ctx, userID)
+}
+
+// Data export...
+
+func (f Facade) GetUserRecord(ctx context.Context, userID string) (models.UserRecord, error) {
+	return f.storage.GetUserRecord(ctx, userID)
+}
+
+func (f Facade) GetLoginAttempts(ctx context.Context, userID string) ([]models.LoginAttempt, error) {
+	return f.storage.GetLoginAttempts(ctx, userID)
+}
+
+func (f Facade) GetEmailChangeRequests(ctx context.Context, userID string) ([]models.EmailChangeRequest, error) {
+	return f.storage.GetEmailChangeRequests(ctx, userID)
+}
+
+// Login...
+
+func (f Facade) CreateLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	return f.storage.CreateLoginAttempt(ctx, attempt)
+}
+
+func (f Facade) CountLoginFailures(ctx context.Context, userID string, since time.Time) (int, error) {
+	return f.storage.CountLoginFailures(ctx, userID, since)
+}
+
+func (f Facade) CountLoginFailuresByMethod(ctx context.Context, userID string, methods []string, since time.Time) (int, error) {
+	return f.storage.CountLoginFailuresByMethod(ctx, userID, methods, since)
+}
+
+func (f Facade) CreateEmailLoginToken(ctx context.Context, userID, method, secretHash string, expiresAt time.Time) error {
+	return f.storage.CreateEmailLoginToken(ctx, userID, method, secretHash, expiresAt)
+}
+
+func (f Facade) RevokeEmailLoginTokens(ctx context.Context, userID string) error {
+	return f.storage.RevokeEmailLoginTokens(ctx, userID)
+}
+
+func (f Facade) ConsumeEmailLoginLink(ctx context.Context, secretHash string) (string, error) {
+	return f.storage.ConsumeEmailLoginLink(ctx, secretHash)
+}
+
+func (f Facade) ConsumeEmailLoginCode(ctx context.Context, userID, secretHash string, maxAttempts int) (bool, error) {
+	return f.storage.ConsumeEmailLoginCode(ctx, userID, secretHash, maxAttempts)
+}
+
+func (f Facade) IncrementEmailLoginAttempts(ctx context.Context, userID string) error {
+	return f.storage.IncrementEmailLoginAttempts(ctx, userID)
+}
+
+// Keys...
+
 func (f Facade) CheckUserRole(ctx context.Context, userID, role string) (bool, error) {
 	return f.storage.CheckUserRole(ctx, userID, role)
 }
 
-// Transactions (activate keys)...
+// MFA...
+
+func (f Facade) UpsertTOTPSecret(ctx context.Context, userID, secret string) (bool, error) {
+	return f.storage.UpsertTOTPSecret(ctx, userID, secret)
+}
+
+func (f Facade) GetTOTP(ctx context.Context, userID string) (models.TOTP, error) {
+	return f.storage.GetTOTP(ctx, userID)
+}
+
+func (f Facade) EnableTOTP(ctx context.Context, userID string, step int64) (bool, error) {
+	return f.storage.EnableTOTP(ctx, userID, step)
+}
+
+func (f Facade) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	return f.storage.UseTOTPStep(ctx, userID, step)
+}
+
+func (f Facade) DeleteTOTP(ctx context.Context, userID string) error {
+	return f.storage.DeleteTOTP(ctx, userID)
+}
+
+func (f Facade) DeleteRecoveryCodes(ctx context.Context, userID string) error {
+	return f.storage.DeleteRecoveryCodes(ctx, userID)
+}
+
+func (f Facade) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	return f.storage.UseRecoveryCode(ctx, userID, codeHash)
+}
+
+func (f Facade) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	return f.storage.CountRecoveryCodes(ctx, userID)
+}
 
-func (f *Facade) ActivateStudent(ctx context.Context, userID, groupID, universityID string, enrollmentYear int) error {
+func (f Facade) CreateWebAuthnCredential(ctx context.Context, userID string, credentialID []byte, name string, credential []byte) error {
+	return f.storage.CreateWebAuthnCredential(ctx, userID, credentialID, name, credential)
+}
+
+func (f Facade) GetWebAuthnCredentials(ctx context.Context, userID string) ([]models.WebAuthnCredential, error) {
+	return f.storage.GetWebAuthnCredentials(ctx, userID)
+}
+
+func (f Facade) UpdateWebAuthnCredential(ctx context.Context, credentialID []byte, credential []byte) error {
+	return f.storage.UpdateWebAuthnCredential(ctx, credentialID, credential)
+}
+
+func (f Facade) DeleteWebAuthnCredential(ctx context.Context, userID, id string) (bool, error) {
+	return f.storage.DeleteWebAuthnCredential(ctx, userID, id)
+}
+
+func (f Facade) CreateWebAuthnSession(ctx context.Context, userID sql.NullString, ceremony string, data []byte, expiresAt time.Time) (string, error) {
+	return f.storage.CreateWebAuthnSession(ctx, userID, ceremony, data, expiresAt)
+}
+
+func (f Facade) TakeWebAuthnSession(ctx context.Context, sessionID, ceremony string) (models.WebAuthnSession, error) {
+	return f.storage.TakeWebAuthnSession(ctx, sessionID, ceremony)
+}
+
+// Roles and permissions...
+
+func (f Facade) GetRoles(ctx context.Context) ([]models.Role, error) {
+	return f.storage.GetRoles(ctx)
+}
+
+func (f Facade) GetPermissions(ctx context.Context) ([]models.Permission, error) {
+	return f.storage.GetPermissions(ctx)
+}
+
+func (f Facade) RoleExists(ctx context.Context, role string) (bool, error) {
+	return f.storage.RoleExists(ctx, role)
+}
+
+func (f Facade) PermissionExists(ctx context.Context, permission string) (bool, error) {
+	return f.storage.PermissionExists(ctx, permission)
+}
+
+func (f Facade) CreateRole(ctx context.Context, role, description string) (bool, error) {
+	return f.storage.CreateRole(ctx, role, description)
+}
+
+func (f Facade) DeleteRole(ctx context.Context, role string) (bool, error) {
+	return f.storage.DeleteRole(ctx, role)
+}
+
+func (f Facade) GrantPermission(ctx context.Context, role, permission string) error {
+	return f.storage.GrantPermission(ctx, role, permission)
+}
+
+func (f Facade) RevokePermission(ctx context.Context, role, permission string) (bool, error) {
+	return f.storage.RevokePermission(ctx, role, permission)
+}
+
+func (f Facade) GetUserPermissions(ctx context.Context, userID string) ([]models.ScopedPermission, error) {
+	return f.storage.GetUserPermissions(ctx, userID)
+}
+
+func (f Facade) HasPermission(ctx context.Context, userID, permission, universityID, groupID string) (bool, error) {
+	return f.storage.HasPermission(ctx, userID, permission, universityID, groupID)
+}
+
+func (f Facade) GetRoleAssignments(ctx context.Context, userID string) ([]models.RoleAssignment, error) {
+	return f.storage.GetRoleAssignments(ctx, userID)
+}
+
+func (f Facade) GrantRole(ctx context.Context, grant models.RoleGrant) (bool, error) {
+	return f.storage.GrantRole(ctx, grant)
+}
+
+func (f Facade) GetRoleMembers(ctx context.Context, role string) ([]models.RoleMember, error) {
+	return f.storage.GetRoleMembers(ctx, role)
+}
+
+// RevokeRole removes the role from the user in the scope, or in every scope
+// when scope is nil. A student or teacher losing their last assignment of
+// the role loses the profile row as well.
+func (f Facade) RevokeRole(ctx context.Context, userID, role string, scope *models.RoleScope) ([]models.RoleAssignment, error) {
 	tx, err := f.storage.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	if err := tx.CreateStudent(ctx, userID, groupID, universityID, enrollmentYear); err != nil {
-		return err
+	revoked, err := tx.RevokeUserRole(ctx, userID, role, scope)
+	if err != nil || len(revoked) == 0 {
+		return nil, err
 	}
 
-	if err := tx.AddUserRole(ctx, userID, "student"); err != nil {
+	if err := deleteUnassignedProfile(ctx, tx, userID, role); err != nil {
+		return nil, err
+	}
+
+	return revoked, tx.Commit()
+}
+
+// ExpireRoles removes up to limit expired role assignments, together with
+// the profile rows they leave without a role.
+func (f Facade) ExpireRoles(ctx context.Context, now time.Time, limit int) ([]models.RoleAssignment, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	expired, err := tx.ExpireUserRoles(ctx, now, limit)
+	if err != nil || len(expired) == 0 {
+		return nil, err
+	}
+
+	for _, assignment := range expired {
+		if err := deleteUnassignedProfile(ctx, tx, assignment.UserID, assignment.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	return expired, tx.Commit()
+}
+
+func deleteUnassignedProfile(ctx context.Context, tx storage.Tx, userID, role string) error {
+	if role != "student" && role != "teacher" {
+		return nil
+	}
+
+	assigned, err := tx.CheckUserRole(ctx, userID, role)
+	if err != nil || assigned {
 		return err
 	}
 
-	return tx.Commit()
+	if role == "student" {
+		return tx.DeleteStudent(ctx, userID)
+	}
+	return tx.DeleteTeacher(ctx, userID)
+}
+
+// Authorization...
+
+func (f Facade) GetTeacherGroups(ctx context.Context, userID string) ([]string, error) {
+	return f.storage.GetTeacherGroups(ctx, userID)
+}
+
+func (f Facade) AddTeacherGroup(ctx context.Context, userID, groupID string) (bool, error) {
+	return f.storage.AddTeacherGroup(ctx, userID, groupID)
+}
+
+func (f Facade) RemoveTeacherGroup(ctx context.Context, userID, groupID string) (bool, error) {
+	return f.storage.RemoveTeacherGroup(ctx, userID, groupID)
+}
+
+func (f Facade) GetUserAffiliation(ctx context.Context, userID string) (models.Affiliation, error) {
+	return f.storage.GetUserAffiliation(ctx, userID)
+}
+
+func (f Facade) GetGroupUniversities(ctx context.Context, groupID string) ([]string, error) {
+	return f.storage.GetGroupUniversities(ctx, groupID)
+}
+
+func (f Facade) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	return f.storage.GetRolePermissions(ctx, role)
+}
+
+// Role requests...
+
+func (f Facade) CreateRoleRequest(ctx context.Context, request models.RoleRequest) (models.RoleRequest, error) {
+	return f.storage.CreateRoleRequest(ctx, request)
+}
+
+func (f Facade) GetRoleRequest(ctx context.Context, requestID string) (models.RoleRequest, error) {
+	return f.storage.GetRoleRequest(ctx, requestID)
+}
+
+func (f Facade) GetRoleRequests(ctx context.Context, status, universityID string) ([]models.RoleRequest, error) {
+	return f.storage.GetRoleRequests(ctx, status, universityID)
+}
+
+func (f Facade) GetUserRoleRequests(ctx context.Context, userID string) ([]models.RoleRequest, error) {
+	return f.storage.GetUserRoleRequests(ctx, userID)
 }
 
-func (f *Facade) ActivateTeacher(ctx context.Context, userID, universityID, degree string) error {
+// RejectRoleRequest returns sql.ErrNoRows when the request is not pending.
+func (f Facade) RejectRoleRequest(ctx context.Context, review models.RoleReview) (models.RoleRequest, error) {
+	review.Status = models.RoleRequestRejected
+	return f.storage.ReviewRoleRequest(ctx, review)
+}
+
+// Transactions (registration)...
+
+// RegisterUser creates the user and, when activation is set, the profile and
+// role granted by an activation key in the same transaction. The key is
+// consumed with the redemption, if any; it reports false, creating nothing,
+// when the key was already redeemed.
+func (f Facade) RegisterUser(ctx context.Context, user models.RegisterUserData, activation *models.RoleActivation, redemption *models.KeyRedemption) (string, bool, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	userID, err := tx.CreateUser(ctx, user)
+	if err != nil {
+		return "", false, err
+	}
+
+	if activation != nil {
+		if err := activateRole(ctx, tx, userID, *activation); err != nil {
+			return "", false, err
+		}
+	}
+
+	if redemption != nil {
+		redemption.UserID = userID
+		if redeemed, err := tx.CreateKeyRedemption(ctx, *redemption); err != nil || !redeemed {
+			return "", false, err
+		}
+	}
+
+	return userID, true, tx.Commit()
+}
+
+// CreateAdmin creates the user together with a global admin role
+// assignment.
+func (f Facade) CreateAdmin(ctx context.Context, user models.RegisterUserData) (string, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	userID, err := tx.CreateUser(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.AddUserRole(ctx, userID, "admin", models.RoleScope{Type: models.ScopeGlobal}); err != nil {
+		return "", err
+	}
+
+	return userID, tx.Commit()
+}
+
+// ResetPassword sets the password hash and revokes all sessions of the user
+// in one transaction. It reports false when the user does not exist.
+func (f Facade) ResetPassword(ctx context.Context, userID, passwordHash string) (bool, error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	updated, err := tx.UpdatePassword(ctx, userID, passwordHash)
+	if err != nil || !updated {
+		return false, err
+	}
+
+	if err := tx.RevokeSessions(ctx, userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Transactions (role requests)...
+
+// ApproveRoleRequest marks the pending request approved and activates the
+// requested role in one transaction. It returns sql.ErrNoRows when the
+// request is not pending. When the user has activated the role in the
+// meantime it rejects the request instead, so that it does not block new
+// requests for the role, and reports false.
+func (f Facade) ApproveRoleRequest(ctx context.Context, review models.RoleReview) (models.RoleRequest, bool, error) {
 	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return models.RoleRequest{}, false, err
+	}
+	defer tx.Rollback()
+
+	request, err := tx.LockRoleRequest(ctx, review.RequestID)
+	if err != nil {
+		return models.RoleRequest{}, false, err
+	}
+
+	activated, err := tx.CheckUserRole(ctx, request.UserID, request.Role)
+	if err != nil {
+		return models.RoleRequest{}, false, err
+	}
+	if activated {
+		review.Status = models.RoleRequestRejected
+		review.Comment = models.RoleRequestAlreadyActivated
+		request, err = tx.ReviewRoleRequest(ctx, review)
+		if err != nil {
+			return models.RoleRequest{}, false, err
+		}
+		return request, false, tx.Commit()
+	}
+
+	review.Status = models.RoleRequestApproved
+	request, err = tx.ReviewRoleRequest(ctx, review)
+	if err != nil {
+		return models.RoleRequest{}, false, err
+	}
+
+	if err := activateRole(ctx, tx, request.UserID, request.Activation()); err != nil {
+		return models.RoleRequest{}, false, err
+	}
+
+	return request, true, tx.Commit()
+}
+
+// activateRole creates the student or teacher profile and the role
+// assignment that come with it.
+func activateRole(ctx context.Context, tx storage.Tx, userID string, activation models.RoleActivation) error {
+	var err error
+	switch activation.Role {
+	case "student":
+		err = tx.CreateStudent(ctx, userID, activation.GroupID, activation.UniversityID, activation.EnrollmentYear)
+	case "teacher":
+		err = tx.CreateTeacher(ctx, userID, activation.UniversityID, activation.Degree)
+	default:
+		err = fmt.Errorf("unknown role %q", activation.Role)
+	}
 	if err != nil {
 		return err
 	}
+
+	return tx.AddUserRole(ctx, userID, activation.Role, activation.Scope())
+}
+
+// Transactions (activate keys)...
+
+// ActivateRole activates the role an activation key grants for an existing
+// user and consumes the key, in one transaction. It reports redeemed false
+// when the key was redeemed before and activated false when the user already
+// has the role; nothing changes in either case.
+func (f Facade) ActivateRole(ctx context.Context, userID string, activation models.RoleActivation, redemption models.KeyRedemption) (redeemed, activated bool, err error) {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return false, false, err
+	}
 	defer tx.Rollback()
 
-	if err := tx.CreateTeacher(ctx, userID, universityID, degree); err != nil {
+	redemption.UserID = userID
+	if redeemed, err := tx.CreateKeyRedemption(ctx, redemption); err != nil || !redeemed {
+		return false, false, err
+	}
+
+	if exists, err := tx.CheckUserRole(ctx, userID, activation.Role); err != nil || exists {
+		return true, false, err
+	}
+
+	if err := activateRole(ctx, tx, userID, activation); err != nil {
+		return true, false, err
+	}
+
+	return true, true, tx.Commit()
+}
+
+// Transactions (recovery codes)...
+
+func (f Facade) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := f.storage.BeginTx(ctx, nil)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	if err := tx.AddUserRole(ctx, userID, "teacher"); err != nil {
+	if err := tx.DeleteRecoveryCodes(ctx, userID); err != nil {
 		return err
 	}
 
+	for _, codeHash := range codeHashes {
+		if err := tx.CreateRecoveryCode(ctx, userID, codeHash); err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit()
 }
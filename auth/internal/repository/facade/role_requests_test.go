@@ -0,0 +1,50 @@
+package facade
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+const testUniversityID = "4d1f0c7e-2a6b-4e3d-9f58-b1c2d3e4f5a6"
+
+func TestApproveRoleRequestClosesRequestForActivatedRole(t *testing.T) {
+	ctx := context.Background()
+	f, _ := newTestFacade(t)
+
+	suffix := time.Now().Format("150405.000000")
+	reviewerID := createTestUser(t, f, "reviewer-"+suffix)
+	teacher := models.RoleActivation{Role: "teacher", UniversityID: testUniversityID, Degree: "PhD"}
+	userID, _, err := f.RegisterUser(ctx, models.RegisterUserData{
+		Username:     "teacher-" + suffix,
+		Email:        "teacher-" + suffix + "@example.com",
+		PasswordHash: "!",
+	}, &teacher, nil)
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+
+	request := models.RoleRequest{UserID: userID, Role: "teacher", UniversityID: testUniversityID}
+	pending, err := f.CreateRoleRequest(ctx, request)
+	if err != nil {
+		t.Fatalf("CreateRoleRequest: %v", err)
+	}
+
+	closed, activated, err := f.ApproveRoleRequest(ctx, models.RoleReview{RequestID: pending.ID, ReviewedBy: reviewerID})
+	if err != nil {
+		t.Fatalf("ApproveRoleRequest: %v", err)
+	}
+	if activated {
+		t.Fatal("ApproveRoleRequest: activated a role the user already has")
+	}
+	if closed.Status != models.RoleRequestRejected || closed.ReviewComment.String != models.RoleRequestAlreadyActivated {
+		t.Fatalf("closed request: got %q, %q, want %q, %q", closed.Status, closed.ReviewComment.String,
+			models.RoleRequestRejected, models.RoleRequestAlreadyActivated)
+	}
+
+	if _, err := f.CreateRoleRequest(ctx, request); err != nil {
+		t.Fatalf("CreateRoleRequest after the request was closed: %v", err)
+	}
+}
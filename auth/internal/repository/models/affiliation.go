@@ -0,0 +1,8 @@
+package models
+
+// Affiliation is where a user belongs: the university of their student or
+// teacher profile and, for students, their group. Fields may be empty.
+type Affiliation struct {
+	UniversityID string `db:"university_id"`
+	GroupID      string `db:"group_id"`
+}
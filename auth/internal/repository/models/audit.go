@@ -0,0 +1,31 @@
+package models
+
+import "database/sql"
+
+type AuditEvent struct {
+	ID        string         `db:"id"`
+	ActorID   sql.NullString `db:"actor_id"`
+	SubjectID sql.NullString `db:"subject_id"`
+	Action    string         `db:"action"`
+	Details   []byte         `db:"details"`
+	IP        sql.NullString `db:"ip"`
+	CreatedAt string         `db:"created_at"`
+}
+
+// KeyIssuance records an activation key handed out. IssuedBy is empty once
+// the issuer is gone.
+type KeyIssuance struct {
+	KeyID      string         `db:"key_id"`
+	IssuedBy   sql.NullString `db:"issued_by"`
+	Role       string         `db:"role"`
+	Attributes []byte         `db:"attributes"`
+	IssuedAt   string         `db:"issued_at"`
+}
+
+type KeyRedemption struct {
+	UserID     string         `db:"user_id"`
+	Role       string         `db:"role"`
+	KeyID      sql.NullString `db:"key_id"`
+	Attributes []byte         `db:"attributes"`
+	RedeemedAt string         `db:"redeemed_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "database/sql"
+
+type EmailChange struct {
+	UserID   string `db:"user_id"`
+	NewEmail string `db:"new_email"`
+}
+
+type EmailChangeRequest struct {
+	OldEmail    string         `db:"old_email"`
+	NewEmail    string         `db:"new_email"`
+	CreatedAt   string         `db:"created_at"`
+	ExpiresAt   string         `db:"expires_at"`
+	ConfirmedAt sql.NullString `db:"confirmed_at"`
+	CancelledAt sql.NullString `db:"cancelled_at"`
+}
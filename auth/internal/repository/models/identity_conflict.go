@@ -0,0 +1,16 @@
+package models
+
+// Fields an identity conflict can be about.
+const (
+	IdentityFieldUsername = "username"
+	IdentityFieldEmail    = "email"
+)
+
+type IdentityConflict struct {
+	ID         string `db:"id"`
+	UserID     string `db:"user_id"`
+	Field      string `db:"field"`
+	Value      string `db:"value"`
+	KeptUserID string `db:"kept_user_id"`
+	DetectedAt string `db:"detected_at"`
+}
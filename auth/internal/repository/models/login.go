@@ -0,0 +1,13 @@
+package models
+
+import "database/sql"
+
+type LoginAttempt struct {
+	UserID    sql.NullString `db:"user_id"`
+	Login     string         `db:"login"`
+	Method    string         `db:"method"`
+	Succeeded bool           `db:"succeeded"`
+	IP        string         `db:"ip"`
+	UserAgent string         `db:"user_agent"`
+	CreatedAt string         `db:"created_at"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// ProfileUpdate holds the editable profile fields. Nil fields are left as is.
+type ProfileUpdate struct {
+	FirstName *string
+	LastName  *string
+	Degree    *string
+}
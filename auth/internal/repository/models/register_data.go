@@ -1,9 +1,30 @@
 package models
 
+import "database/sql"
+
 type RegisterUserData struct {
-	Username     string `db:"username"`
-	Email        string `db:"email"`
-	PasswordHash string `db:"password_hash"`
-	FirstName    string `db:"first_name"`
-	LastName     string `db:"last_name"`
+	Username     string         `db:"username"`
+	Email        string         `db:"email"`
+	PasswordHash string         `db:"password_hash"`
+	FirstName    string         `db:"first_name"`
+	LastName     string         `db:"last_name"`
+	UniversityID sql.NullString `db:"university_id"`
+}
+
+// RoleActivation is the profile an activation key grants.
+type RoleActivation struct {
+	Role           string
+	GroupID        string
+	UniversityID   string
+	EnrollmentYear int
+	Degree         string
+}
+
+// Scope is where the activated role applies: students are scoped to their
+// group and teachers to their university.
+func (a RoleActivation) Scope() RoleScope {
+	if a.Role == "student" {
+		return RoleScope{Type: ScopeGroup, ID: a.GroupID}
+	}
+	return RoleScope{Type: ScopeUniversity, ID: a.UniversityID}
 }
@@ -0,0 +1,61 @@
+package models
+
+import "database/sql"
+
+type Role struct {
+	Name        string   `db:"name"`
+	Description string   `db:"description"`
+	Permissions []string `db:"permissions"`
+}
+
+type Permission struct {
+	Name        string `db:"name"`
+	Description string `db:"description"`
+}
+
+const (
+	ScopeGlobal     = "global"
+	ScopeUniversity = "university"
+	ScopeGroup      = "group"
+)
+
+// RoleScope limits a role assignment. ID is empty for global assignments.
+type RoleScope struct {
+	Type string `db:"scope_type"`
+	ID   string `db:"scope_id"`
+}
+
+type RoleAssignment struct {
+	UserID string `db:"user_id"`
+	Role   string `db:"role"`
+	RoleScope
+	GrantedAt string         `db:"granted_at"`
+	GrantedBy sql.NullString `db:"granted_by"`
+	ExpiresAt sql.NullString `db:"expires_at"`
+}
+
+// RoleGrant is a role assignment about to be created. GrantedBy is empty for
+// roles obtained through activation keys, ExpiresAt for permanent ones.
+type RoleGrant struct {
+	UserID string
+	Role   string
+	RoleScope
+	GrantedBy sql.NullString
+	ExpiresAt sql.NullTime
+}
+
+type ScopedPermission struct {
+	Permission string `db:"permission"`
+	RoleScope
+}
+
+// RoleMember is a user holding a role globally.
+type RoleMember struct {
+	UserID    string         `db:"user_id"`
+	Username  string         `db:"username"`
+	Email     string         `db:"email"`
+	Active    bool           `db:"active"`
+	GrantedAt string         `db:"granted_at"`
+	GrantedBy sql.NullString `db:"granted_by"`
+	ExpiresAt sql.NullString `db:"expires_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "database/sql"
+
+const (
+	RoleRequestPending  = "pending"
+	RoleRequestApproved = "approved"
+	RoleRequestRejected = "rejected"
+
+	// RoleRequestAlreadyActivated is the review comment of a request closed
+	// because the user got the role some other way.
+	RoleRequestAlreadyActivated = "role already activated"
+)
+
+type RoleRequest struct {
+	ID             string         `db:"id"`
+	UserID         string         `db:"user_id"`
+	Role           string         `db:"role"`
+	UniversityID   string         `db:"university_id"`
+	GroupID        sql.NullString `db:"group_id"`
+	EnrollmentYear sql.NullInt64  `db:"enrollment_year"`
+	Degree         sql.NullString `db:"degree"`
+	Comment        string         `db:"comment"`
+	Status         string         `db:"status"`
+	ReviewedBy     sql.NullString `db:"reviewed_by"`
+	ReviewComment  sql.NullString `db:"review_comment"`
+	ReviewedAt     sql.NullString `db:"reviewed_at"`
+	CreatedAt      string         `db:"created_at"`
+}
+
+// Activation is the profile granted when the request is approved.
+func (r RoleRequest) Activation() RoleActivation {
+	return RoleActivation{
+		Role:           r.Role,
+		GroupID:        r.GroupID.String,
+		UniversityID:   r.UniversityID,
+		EnrollmentYear: int(r.EnrollmentYear.Int64),
+		Degree:         r.Degree.String,
+	}
+}
+
+// RoleReview is the decision of an approver on a pending request.
+type RoleReview struct {
+	RequestID  string
+	Status     string
+	ReviewedBy string
+	Comment    string
+}
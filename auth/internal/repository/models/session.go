@@ -0,0 +1,8 @@
+package models
+
+import "database/sql"
+
+type SessionState struct {
+	RevokedAt sql.NullTime `db:"sessions_revoked_at"`
+	Active    bool         `db:"active"`
+}
@@ -0,0 +1,8 @@
+package models
+
+type TOTP struct {
+	UserID       string `db:"user_id"`
+	Secret       string `db:"secret"`
+	Enabled      bool   `db:"enabled"`
+	LastUsedStep int64  `db:"last_used_step"`
+}
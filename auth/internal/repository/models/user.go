@@ -1,10 +1,21 @@
 package models
 
+import "database/sql"
+
 type User struct {
-	ID        string `db:"id"`
-	Username  string `db:"username"`
-	Email     string `db:"email"`
-	FirstName string `db:"first_name"`
-	LastName  string `db:"last_name"`
-	CreatedAt string `db:"created_at"`
+	ID           string `db:"id"`
+	Username     string `db:"username"`
+	Email        string `db:"email"`
+	FirstName    string `db:"first_name"`
+	LastName     string `db:"last_name"`
+	CreatedAt    string `db:"created_at"`
+	UniversityID string `db:"university_id"`
+}
+
+// UserRecord is the full users row without the password hash.
+type UserRecord struct {
+	User
+	SessionsRevokedAt sql.NullString `db:"sessions_revoked_at"`
+	DeactivatedAt     sql.NullString `db:"deactivated_at"`
+	DeletedAt         sql.NullString `db:"deleted_at"`
 }
@@ -0,0 +1,7 @@
+package models
+
+type UsernameHistory struct {
+	Username      string `db:"username"`
+	ChangedAt     string `db:"changed_at"`
+	ReservedUntil string `db:"reserved_until"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "database/sql"
+
+type WebAuthnCredential struct {
+	ID           string         `db:"id"`
+	UserID       string         `db:"user_id"`
+	CredentialID []byte         `db:"credential_id"`
+	Name         string         `db:"name"`
+	Credential   []byte         `db:"credential"`
+	CreatedAt    string         `db:"created_at"`
+	LastUsedAt   sql.NullString `db:"last_used_at"`
+}
+
+type WebAuthnSession struct {
+	ID       string         `db:"id"`
+	UserID   sql.NullString `db:"user_id"`
+	Ceremony string         `db:"ceremony"`
+	Data     []byte         `db:"data"`
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Account status...
+
+func (s *DBStorage) IsUserActive(ctx context.Context, userID string) (bool, error) {
+	var active bool
+	err := s.db.QueryRowContext(ctx, storage.IsUserActiveQuery, userID).Scan(&active)
+	return active, err
+}
+
+func (s *DBStorage) DeactivateUser(ctx context.Context, userID string) (bool, error) {
+	return execAffected(s.db.ExecContext(ctx, storage.DeactivateUserQuery, userID))
+}
+
+func (s *DBStorage) SoftDeleteUser(ctx context.Context, userID string) (bool, error) {
+	return execAffected(s.db.ExecContext(ctx, storage.SoftDeleteUserQuery, userID))
+}
+
+func (s *DBStorage) ReactivateUser(ctx context.Context, userID string) (bool, error) {
+	return execAffected(s.db.ExecContext(ctx, storage.ReactivateUserQuery, userID))
+}
+
+func (s *DBStorage) GetUsersToErase(ctx context.Context, deletedBefore time.Time, limit int) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetUsersToEraseQuery, deletedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
+// Account status (transactions)...
+
+func (s *storageTx) EraseUser(ctx context.Context, userID string) (bool, error) {
+	return execAffected(s.tx.ExecContext(ctx, storage.EraseUserQuery, userID))
+}
+
+func (s *storageTx) EraseTeacher(ctx context.Context, userID string) error {
+	_, err := s.tx.ExecContext(ctx, storage.EraseTeacherQuery, userID)
+	return err
+}
+
+func (s *storageTx) DeleteUserPersonalData(ctx context.Context, userID string) error {
+	_, err := s.tx.ExecContext(ctx, storage.DeleteUserPersonalDataQuery, userID)
+	return err
+}
+
+// UpdatePassword reports false when the user does not exist or is deleted.
+func (s *storageTx) UpdatePassword(ctx context.Context, userID, passwordHash string) (bool, error) {
+	return execAffected(s.tx.ExecContext(ctx, storage.UpdatePasswordQuery, userID, passwordHash))
+}
+
+func execAffected(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Audit...
+
+func (s *DBStorage) CreateAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	_, err := s.db.ExecContext(ctx, storage.CreateAuditEventQuery,
+		event.ActorID, event.SubjectID, event.Action, event.Details, event.IP)
+	return err
+}
+
+func (s *DBStorage) GetAuditEventsByUser(ctx context.Context, userID string) ([]models.AuditEvent, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetAuditEventsByUserQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var event models.AuditEvent
+		if err := rows.Scan(&event.ID, &event.ActorID, &event.SubjectID, &event.Action,
+			&event.Details, &event.IP, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// Activation key redemptions...
+
+func (s *DBStorage) CreateKeyIssuance(ctx context.Context, issuance models.KeyIssuance) error {
+	_, err := s.db.ExecContext(ctx, storage.CreateKeyIssuanceQuery,
+		issuance.KeyID, issuance.IssuedBy, issuance.Role, issuance.Attributes)
+	return err
+}
+
+// Activation keys (transactions)...
+
+func (s *storageTx) LockKeyIssuer(ctx context.Context, issuedBy string) error {
+	var id string
+	return s.tx.QueryRowContext(ctx, storage.LockKeyIssuerQuery, issuedBy).Scan(&id)
+}
+
+func (s *storageTx) CountKeyIssuances(ctx context.Context, issuedBy string, since time.Time) (int, error) {
+	var count int
+	err := s.tx.QueryRowContext(ctx, storage.CountKeyIssuancesQuery, issuedBy, since).Scan(&count)
+	return count, err
+}
+
+func (s *storageTx) CreateKeyIssuance(ctx context.Context, issuance models.KeyIssuance) error {
+	_, err := s.tx.ExecContext(ctx, storage.CreateKeyIssuanceQuery,
+		issuance.KeyID, issuance.IssuedBy, issuance.Role, issuance.Attributes)
+	return err
+}
+
+// CreateKeyRedemption reports false when a key with the same ID was already
+// redeemed.
+func (s *storageTx) CreateKeyRedemption(ctx context.Context, redemption models.KeyRedemption) (bool, error) {
+	_, err := s.tx.ExecContext(ctx, storage.CreateKeyRedemptionQuery,
+		redemption.UserID, redemption.Role, redemption.KeyID, redemption.Attributes)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *DBStorage) GetKeyIssuances(ctx context.Context, issuedBy string) ([]models.KeyIssuance, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetKeyIssuancesQuery, issuedBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issuances []models.KeyIssuance
+	for rows.Next() {
+		issuance := models.KeyIssuance{IssuedBy: sql.NullString{String: issuedBy, Valid: true}}
+		if err := rows.Scan(&issuance.KeyID, &issuance.Role, &issuance.Attributes, &issuance.IssuedAt); err != nil {
+			return nil, err
+		}
+		issuances = append(issuances, issuance)
+	}
+
+	return issuances, rows.Err()
+}
+
+func (s *DBStorage) GetKeyRedemptions(ctx context.Context, userID string) ([]models.KeyRedemption, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetKeyRedemptionsQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var redemptions []models.KeyRedemption
+	for rows.Next() {
+		redemption := models.KeyRedemption{UserID: userID}
+		if err := rows.Scan(&redemption.Role, &redemption.KeyID, &redemption.Attributes, &redemption.RedeemedAt); err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	return redemptions, rows.Err()
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Teacher groups...
+
+func (s *DBStorage) GetTeacherGroups(ctx context.Context, userID string) ([]string, error) {
+	return scanStrings(s.db.QueryContext(ctx, storage.GetTeacherGroupsQuery, userID))
+}
+
+// AddTeacherGroup reports false when the teacher already teaches the group.
+func (s *DBStorage) AddTeacherGroup(ctx context.Context, userID, groupID string) (bool, error) {
+	return execAffected(s.db.ExecContext(ctx, storage.AddTeacherGroupQuery, userID, groupID))
+}
+
+func (s *DBStorage) RemoveTeacherGroup(ctx context.Context, userID, groupID string) (bool, error) {
+	return execAffected(s.db.ExecContext(ctx, storage.RemoveTeacherGroupQuery, userID, groupID))
+}
+
+// Authorization...
+
+func (s *DBStorage) GetUserAffiliation(ctx context.Context, userID string) (models.Affiliation, error) {
+	var affiliation models.Affiliation
+	err := s.db.QueryRowContext(ctx, storage.GetUserAffiliationQuery, userID).
+		Scan(&affiliation.UniversityID, &affiliation.GroupID)
+	return affiliation, err
+}
+
+// GetGroupUniversities returns up to two universities of the students of the
+// group, none when no student has a university.
+func (s *DBStorage) GetGroupUniversities(ctx context.Context, groupID string) ([]string, error) {
+	return scanStrings(s.db.QueryContext(ctx, storage.GetGroupUniversitiesQuery, groupID))
+}
+
+func (s *DBStorage) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	return scanStrings(s.db.QueryContext(ctx, storage.GetRolePermissionsQuery, role))
+}
+
+func scanStrings(rows *sql.Rows, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+const uniqueViolation = "23505"
+
+// Email change...
+
+func (s *DBStorage) CreateEmailChangeRequest(ctx context.Context, userID, oldEmail, newEmail, confirmHash, cancelHash string, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, storage.CreateEmailChangeRequestQuery,
+		userID, oldEmail, newEmail, confirmHash, cancelHash, expiresAt)
+	return err
+}
+
+func (s *DBStorage) CancelPendingEmailChanges(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(ctx, storage.CancelPendingEmailChangesQuery, userID)
+	return err
+}
+
+func (s *DBStorage) CancelEmailChange(ctx context.Context, cancelHash string) (string, error) {
+	var userID string
+	err := s.db.QueryRowContext(ctx, storage.CancelEmailChangeQuery, cancelHash).Scan(&userID)
+	return userID, err
+}
+
+// Sessions...
+
+func (s *DBStorage) RevokeSessions(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(ctx, storage.RevokeSessionsQuery, userID)
+	return err
+}
+
+func (s *DBStorage) GetSessionState(ctx context.Context, userID string) (models.SessionState, error) {
+	var state models.SessionState
+	err := s.db.QueryRowContext(ctx, storage.GetSessionStateQuery, userID).Scan(&state.RevokedAt, &state.Active)
+	return state, err
+}
+
+// Email change (transactions)...
+
+func (s *storageTx) ConfirmEmailChange(ctx context.Context, confirmHash string) (models.EmailChange, error) {
+	var change models.EmailChange
+	err := s.tx.QueryRowContext(ctx, storage.ConfirmEmailChangeQuery, confirmHash).Scan(&change.UserID, &change.NewEmail)
+	return change, err
+}
+
+// UpdateUserEmail reports false when the address is already taken.
+func (s *storageTx) UpdateUserEmail(ctx context.Context, userID, email string) (bool, error) {
+	_, err := s.tx.ExecContext(ctx, storage.UpdateUserEmailQuery, userID, email)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *storageTx) RevokeSessions(ctx context.Context, userID string) error {
+	_, err := s.tx.ExecContext(ctx, storage.RevokeSessionsQuery, userID)
+	return err
+}
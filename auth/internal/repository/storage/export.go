@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Data export...
+
+func (s *DBStorage) GetUserRecord(ctx context.Context, userID string) (models.UserRecord, error) {
+	var user models.UserRecord
+	err := s.db.QueryRowContext(ctx, storage.GetUserRecordQuery, userID).Scan(&user.ID, &user.Username, &user.Email,
+		&user.FirstName, &user.LastName, &user.CreatedAt, &user.UniversityID, &user.SessionsRevokedAt, &user.DeactivatedAt, &user.DeletedAt)
+	return user, err
+}
+
+func (s *DBStorage) GetLoginAttempts(ctx context.Context, userID string) ([]models.LoginAttempt, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetLoginAttemptsQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []models.LoginAttempt
+	for rows.Next() {
+		var attempt models.LoginAttempt
+		if err := rows.Scan(&attempt.UserID, &attempt.Login, &attempt.Method, &attempt.Succeeded,
+			&attempt.IP, &attempt.UserAgent, &attempt.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+func (s *DBStorage) GetEmailChangeRequests(ctx context.Context, userID string) ([]models.EmailChangeRequest, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetEmailChangeRequestsQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.EmailChangeRequest
+	for rows.Next() {
+		var request models.EmailChangeRequest
+		if err := rows.Scan(&request.OldEmail, &request.NewEmail, &request.CreatedAt, &request.ExpiresAt,
+			&request.ConfirmedAt, &request.CancelledAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, rows.Err()
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Identity conflicts...
+
+func (s *DBStorage) GetIdentityConflicts(ctx context.Context) ([]models.IdentityConflict, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetIdentityConflictsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conflicts []models.IdentityConflict
+	for rows.Next() {
+		var c models.IdentityConflict
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Field, &c.Value, &c.KeptUserID, &c.DetectedAt); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, c)
+	}
+
+	return conflicts, rows.Err()
+}
+
+func (s *DBStorage) GetIdentityConflict(ctx context.Context, conflictID string) (models.IdentityConflict, error) {
+	var c models.IdentityConflict
+	err := s.db.QueryRowContext(ctx, storage.GetIdentityConflictQuery, conflictID).
+		Scan(&c.ID, &c.UserID, &c.Field, &c.Value, &c.KeptUserID, &c.DetectedAt)
+	return c, err
+}
+
+// Identity conflicts (transactions)...
+
+func (s *storageTx) LockIdentityConflict(ctx context.Context, conflictID string) (models.IdentityConflict, error) {
+	var c models.IdentityConflict
+	err := s.tx.QueryRowContext(ctx, storage.LockIdentityConflictQuery, conflictID).
+		Scan(&c.ID, &c.UserID, &c.Field, &c.Value, &c.KeptUserID, &c.DetectedAt)
+	return c, err
+}
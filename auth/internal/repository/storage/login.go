@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Login attempts...
+
+func (s *DBStorage) CreateLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	_, err := s.db.ExecContext(ctx, storage.CreateLoginAttemptQuery, attempt.UserID, attempt.Login,
+		attempt.Method, attempt.Succeeded, attempt.IP, attempt.UserAgent)
+	return err
+}
+
+func (s *DBStorage) CountLoginFailures(ctx context.Context, userID string, since time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, storage.CountLoginFailuresQuery, userID, since).Scan(&count)
+	return count, err
+}
+
+func (s *DBStorage) CountLoginFailuresByMethod(ctx context.Context, userID string, methods []string, since time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, storage.CountLoginFailuresByMethodQuery, userID, pq.Array(methods), since).Scan(&count)
+	return count, err
+}
+
+// Email login...
+
+func (s *DBStorage) CreateEmailLoginToken(ctx context.Context, userID, method, secretHash string, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, storage.CreateEmailLoginTokenQuery, userID, method, secretHash, expiresAt)
+	return err
+}
+
+func (s *DBStorage) RevokeEmailLoginTokens(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(ctx, storage.RevokeEmailLoginTokensQuery, userID)
+	return err
+}
+
+func (s *DBStorage) ConsumeEmailLoginLink(ctx context.Context, secretHash string) (string, error) {
+	var userID string
+	err := s.db.QueryRowContext(ctx, storage.ConsumeEmailLoginLinkQuery, secretHash).Scan(&userID)
+	return userID, err
+}
+
+func (s *DBStorage) ConsumeEmailLoginCode(ctx context.Context, userID, secretHash string, maxAttempts int) (bool, error) {
+	res, err := s.db.ExecContext(ctx, storage.ConsumeEmailLoginCodeQuery, userID, secretHash, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *DBStorage) IncrementEmailLoginAttempts(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(ctx, storage.IncrementEmailLoginAttemptsQuery, userID)
+	return err
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// TOTP...
+
+func (s *DBStorage) UpsertTOTPSecret(ctx context.Context, userID, secret string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, storage.UpsertTOTPSecretQuery, userID, secret)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *DBStorage) GetTOTP(ctx context.Context, userID string) (models.TOTP, error) {
+	var totp models.TOTP
+	err := s.db.QueryRowContext(ctx, storage.GetTOTPQuery, userID).Scan(
+		&totp.UserID, &totp.Secret, &totp.Enabled, &totp.LastUsedStep)
+	return totp, err
+}
+
+func (s *DBStorage) EnableTOTP(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, storage.EnableTOTPQuery, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *DBStorage) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, storage.UseTOTPStepQuery, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *DBStorage) DeleteTOTP(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(ctx, storage.DeleteTOTPQuery, userID)
+	return err
+}
+
+// Recovery codes...
+
+func (s *DBStorage) DeleteRecoveryCodes(ctx context.Context, userID string) error {
+	_, err := s.db.ExecContext(ctx, storage.DeleteRecoveryCodesQuery, userID)
+	return err
+}
+
+func (s *DBStorage) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, storage.UseRecoveryCodeQuery, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *DBStorage) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, storage.CountRecoveryCodesQuery, userID).Scan(&count)
+	return count, err
+}
+
+// storageTx (transactions)
+
+func (s *storageTx) CreateRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	_, err := s.tx.ExecContext(ctx, storage.CreateRecoveryCodeQuery, userID, codeHash)
+	return err
+}
+
+func (s *storageTx) DeleteRecoveryCodes(ctx context.Context, userID string) error {
+	_, err := s.tx.ExecContext(ctx, storage.DeleteRecoveryCodesQuery, userID)
+	return err
+}
+
+// WebAuthn...
+
+func (s *DBStorage) CreateWebAuthnCredential(ctx context.Context, userID string, credentialID []byte, name string, credential []byte) error {
+	_, err := s.db.ExecContext(ctx, storage.CreateWebAuthnCredentialQuery, userID, credentialID, name, credential)
+	return err
+}
+
+func (s *DBStorage) GetWebAuthnCredentials(ctx context.Context, userID string) ([]models.WebAuthnCredential, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetWebAuthnCredentialsQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []models.WebAuthnCredential
+	for rows.Next() {
+		var credential models.WebAuthnCredential
+		if err := rows.Scan(&credential.ID, &credential.UserID, &credential.CredentialID, &credential.Name,
+			&credential.Credential, &credential.CreatedAt, &credential.LastUsedAt); err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, rows.Err()
+}
+
+func (s *DBStorage) UpdateWebAuthnCredential(ctx context.Context, credentialID []byte, credential []byte) error {
+	_, err := s.db.ExecContext(ctx, storage.UpdateWebAuthnCredentialQuery, credentialID, credential)
+	return err
+}
+
+func (s *DBStorage) DeleteWebAuthnCredential(ctx context.Context, userID, id string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, storage.DeleteWebAuthnCredentialQuery, userID, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *DBStorage) CreateWebAuthnSession(ctx context.Context, userID sql.NullString, ceremony string, data []byte, expiresAt time.Time) (string, error) {
+	var sessionID string
+	err := s.db.QueryRowContext(ctx, storage.CreateWebAuthnSessionQuery, userID, ceremony, data, expiresAt).Scan(&sessionID)
+	return sessionID, err
+}
+
+func (s *DBStorage) TakeWebAuthnSession(ctx context.Context, sessionID, ceremony string) (models.WebAuthnSession, error) {
+	var session models.WebAuthnSession
+	err := s.db.QueryRowContext(ctx, storage.TakeWebAuthnSessionQuery, sessionID, ceremony).Scan(
+		&session.ID, &session.UserID, &session.Ceremony, &session.Data)
+	return session, err
+}
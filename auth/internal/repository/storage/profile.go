@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Profile (transactions)...
+
+func (s *storageTx) UpdateUser(ctx context.Context, userID string, firstName, lastName *string) (bool, error) {
+	res, err := s.tx.ExecContext(ctx, storage.UpdateUserQuery, userID, firstName, lastName)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *storageTx) UpdateTeacher(ctx context.Context, userID string, degree *string) (bool, error) {
+	res, err := s.tx.ExecContext(ctx, storage.UpdateTeacherQuery, userID, degree)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	AddTeacherGroupQuery = `
+	INSERT INTO teacher_groups (user_id, group_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING`
+)
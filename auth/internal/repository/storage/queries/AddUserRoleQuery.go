@@ -2,7 +2,7 @@ package storage
 
 const (
 	AddUserRoleQuery = `
-        INSERT INTO user_roles (user_id, role_id)
-        SELECT $1, id FROM roles WHERE name = $2
+        INSERT INTO user_roles (user_id, role_id, scope_type, scope_id)
+        SELECT $1, id, $3, $4 FROM roles WHERE name = $2
     `
 )
@@ -0,0 +1,10 @@
+package storage
+
+const (
+	CancelEmailChangeQuery = `
+		UPDATE email_change_requests
+		SET cancelled_at = now()
+		WHERE cancel_hash = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL
+		RETURNING user_id
+	`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	CancelPendingEmailChangesQuery = `
+		UPDATE email_change_requests
+		SET cancelled_at = now()
+		WHERE user_id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL
+	`
+)
@@ -6,6 +6,7 @@ const (
 			SELECT 1 FROM user_roles ur
 			JOIN roles r ON ur.role_id = r.id
 			WHERE ur.user_id = $1 AND r.name = $2
+			  AND (ur.expires_at IS NULL OR ur.expires_at > now())
 		)
 	`
 )
@@ -0,0 +1,10 @@
+package storage
+
+const (
+	ConfirmEmailChangeQuery = `
+		UPDATE email_change_requests
+		SET confirmed_at = now()
+		WHERE confirm_hash = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > now()
+		RETURNING user_id, new_email
+	`
+)
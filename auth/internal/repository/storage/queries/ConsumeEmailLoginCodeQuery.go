@@ -0,0 +1,10 @@
+package storage
+
+const (
+	ConsumeEmailLoginCodeQuery = `
+		UPDATE email_login_tokens
+		SET used_at = now()
+		WHERE user_id = $1 AND secret_hash = $2 AND method = 'code'
+		AND used_at IS NULL AND expires_at > now() AND attempts < $3
+	`
+)
@@ -0,0 +1,10 @@
+package storage
+
+const (
+	ConsumeEmailLoginLinkQuery = `
+		UPDATE email_login_tokens
+		SET used_at = now()
+		WHERE secret_hash = $1 AND method = 'link' AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id
+	`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	CountKeyIssuancesQuery = `
+		SELECT COUNT(*)
+		FROM activation_key_issuances
+		WHERE issued_by = $1 AND issued_at >= $2
+	`
+)
@@ -0,0 +1,16 @@
+package storage
+
+// CountLoginFailuresByMethodQuery counts failed attempts with one of the
+// methods $2 since $3 that happened after the last successful attempt with
+// one of those methods.
+const (
+	CountLoginFailuresByMethodQuery = `
+		SELECT COUNT(*)
+		FROM login_attempts
+		WHERE user_id = $1 AND method = ANY($2) AND succeeded = false AND created_at > $3
+		AND created_at > COALESCE(
+			(SELECT MAX(created_at) FROM login_attempts WHERE user_id = $1 AND method = ANY($2) AND succeeded = true),
+			'-infinity'::timestamp
+		)
+	`
+)
@@ -0,0 +1,15 @@
+package storage
+
+// CountLoginFailuresQuery counts failed attempts since $2 that happened
+// after the last successful login.
+const (
+	CountLoginFailuresQuery = `
+		SELECT COUNT(*)
+		FROM login_attempts
+		WHERE user_id = $1 AND succeeded = false AND created_at > $2
+		AND created_at > COALESCE(
+			(SELECT MAX(created_at) FROM login_attempts WHERE user_id = $1 AND succeeded = true),
+			'-infinity'::timestamp
+		)
+	`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	CountRecoveryCodesQuery = `
+		SELECT COUNT(*)
+		FROM mfa_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	CreateAuditEventQuery = `
+		INSERT INTO audit_events (actor_id, subject_id, action, details, ip)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+)
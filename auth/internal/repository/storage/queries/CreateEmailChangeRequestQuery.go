@@ -0,0 +1,8 @@
+package storage
+
+const (
+	CreateEmailChangeRequestQuery = `
+		INSERT INTO email_change_requests (user_id, old_email, new_email, confirm_hash, cancel_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	CreateEmailLoginTokenQuery = `
+		INSERT INTO email_login_tokens (user_id, method, secret_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	CreateKeyIssuanceQuery = `
+		INSERT INTO activation_key_issuances (key_id, issued_by, role, attributes)
+		VALUES ($1, $2, $3, $4)
+	`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	CreateKeyRedemptionQuery = `
+		INSERT INTO activation_key_redemptions (user_id, role, key_id, attributes)
+		VALUES ($1, $2, $3, $4)
+	`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	CreateLoginAttemptQuery = `
+		INSERT INTO login_attempts (user_id, login, method, succeeded, ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+)
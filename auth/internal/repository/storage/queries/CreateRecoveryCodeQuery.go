@@ -0,0 +1,8 @@
+package storage
+
+const (
+	CreateRecoveryCodeQuery = `
+		INSERT INTO mfa_recovery_codes (user_id, code_hash)
+		VALUES ($1, $2)
+	`
+)
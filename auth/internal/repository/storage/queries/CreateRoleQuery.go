@@ -0,0 +1,7 @@
+package storage
+
+const (
+	CreateRoleQuery = `
+	INSERT INTO roles (name, description)
+	VALUES ($1, $2)`
+)
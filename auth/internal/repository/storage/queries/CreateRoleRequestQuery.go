@@ -0,0 +1,10 @@
+package storage
+
+const (
+	CreateRoleRequestQuery = `
+	INSERT INTO role_requests (user_id, role, university_id, group_id, enrollment_year, degree, comment)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (user_id, role) WHERE status = 'pending' DO NOTHING
+	RETURNING id, user_id, role, university_id, group_id::text, enrollment_year, degree, comment, status,
+	reviewed_by::text, review_comment, reviewed_at, created_at`
+)
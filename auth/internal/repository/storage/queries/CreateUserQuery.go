@@ -7,8 +7,11 @@ const (
             email,
             password_hash,
             first_name,
-            last_name
-        ) VALUES ($1, $2, $3, $4, $5)
+            last_name,
+            username_canonical,
+            email_canonical,
+            university_id
+        ) VALUES ($1, $2, $3, $4, $5, lower(normalize($1, NFKC)), lower(normalize($2, NFKC)), $6)
         RETURNING id
     `
 )
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	CreateUsernameHistoryQuery = `
+		INSERT INTO username_history (user_id, username, username_canonical, reserved_until)
+		VALUES ($1, $2, lower(normalize($2, NFKC)), $3)
+	`
+)
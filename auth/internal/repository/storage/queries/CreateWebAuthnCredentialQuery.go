@@ -0,0 +1,8 @@
+package storage
+
+const (
+	CreateWebAuthnCredentialQuery = `
+		INSERT INTO webauthn_credentials (user_id, credential_id, name, credential)
+		VALUES ($1, $2, $3, $4)
+	`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	CreateWebAuthnSessionQuery = `
+		INSERT INTO webauthn_sessions (user_id, ceremony, data, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+)
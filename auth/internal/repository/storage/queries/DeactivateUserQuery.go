@@ -0,0 +1,9 @@
+package storage
+
+const (
+	DeactivateUserQuery = `
+	UPDATE users
+	SET deactivated_at = COALESCE(deactivated_at, now()),
+	    sessions_revoked_at = now()
+	WHERE id = $1 AND erased_at IS NULL`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	DeleteRecoveryCodesQuery = `
+		DELETE FROM mfa_recovery_codes
+		WHERE user_id = $1
+	`
+)
@@ -0,0 +1,7 @@
+package storage
+
+const (
+	DeleteRoleQuery = `
+	DELETE FROM roles
+	WHERE name = $1`
+)
@@ -0,0 +1,5 @@
+package storage
+
+const (
+	DeleteStudentQuery = `DELETE FROM students WHERE user_id = $1`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	DeleteTOTPQuery = `
+		DELETE FROM user_totp
+		WHERE user_id = $1
+	`
+)
@@ -0,0 +1,5 @@
+package storage
+
+const (
+	DeleteTeacherQuery = `DELETE FROM teachers WHERE user_id = $1`
+)
@@ -0,0 +1,28 @@
+package storage
+
+// DeleteUserPersonalDataQuery keeps the audit events but drops the IPs and
+// the detail keys that hold identifiers of the person, e.g. old usernames.
+// Role requests stay for the reviewers' history without their free text.
+const (
+	DeleteUserPersonalDataQuery = `
+	WITH
+		attempts AS (DELETE FROM login_attempts WHERE user_id = $1),
+		email_logins AS (DELETE FROM email_login_tokens WHERE user_id = $1),
+		email_changes AS (DELETE FROM email_change_requests WHERE user_id = $1),
+		usernames AS (DELETE FROM username_history WHERE user_id = $1),
+		totp AS (DELETE FROM user_totp WHERE user_id = $1),
+		recovery_codes AS (DELETE FROM mfa_recovery_codes WHERE user_id = $1),
+		webauthn_sessions AS (DELETE FROM webauthn_sessions WHERE user_id = $1),
+		identity_conflicts AS (DELETE FROM identity_conflicts WHERE user_id = $1),
+		role_requests AS (
+			UPDATE role_requests
+			SET comment = '', review_comment = NULL
+			WHERE user_id = $1
+		),
+		audit_events AS (
+			UPDATE audit_events
+			SET ip = NULL, details = details - '{username,email}'::text[]
+			WHERE subject_id = $1 OR actor_id = $1
+		)
+	DELETE FROM webauthn_credentials WHERE user_id = $1`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	DeleteWebAuthnCredentialQuery = `
+		DELETE FROM webauthn_credentials
+		WHERE user_id = $1 AND id = $2
+	`
+)
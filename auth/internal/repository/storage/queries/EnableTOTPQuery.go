@@ -0,0 +1,9 @@
+package storage
+
+const (
+	EnableTOTPQuery = `
+		UPDATE user_totp
+		SET enabled = true, enabled_at = now(), last_used_step = $2
+		WHERE user_id = $1 AND enabled = false
+	`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	EraseTeacherQuery = `
+	UPDATE teachers
+	SET degree = ''
+	WHERE user_id = $1`
+)
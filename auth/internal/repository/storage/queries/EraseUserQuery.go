@@ -0,0 +1,18 @@
+package storage
+
+const (
+	// EraseUserQuery replaces personal data with placeholders but keeps the
+	// row, so IDs referenced by other services stay resolvable.
+	EraseUserQuery = `
+	UPDATE users
+	SET username = 'erased-' || id,
+	    email = 'erased-' || id || '@invalid',
+	    username_canonical = 'erased-' || id,
+	    email_canonical = 'erased-' || id || '@invalid',
+	    password_hash = '!',
+	    first_name = '',
+	    last_name = '',
+	    erased_at = now(),
+	    sessions_revoked_at = now()
+	WHERE id = $1 AND deleted_at IS NOT NULL AND erased_at IS NULL`
+)
@@ -0,0 +1,17 @@
+package storage
+
+const (
+	ExpireUserRolesQuery = `
+	DELETE FROM user_roles ur
+	USING roles r
+	WHERE ur.role_id = r.id
+	  AND (ur.user_id, ur.role_id, ur.scope_type, ur.scope_id) IN (
+		SELECT user_id, role_id, scope_type, scope_id
+		FROM user_roles
+		WHERE expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	  )
+	RETURNING ur.user_id, r.name, ur.scope_type, ur.scope_id, ur.granted_at, ur.granted_by::text, ur.expires_at`
+)
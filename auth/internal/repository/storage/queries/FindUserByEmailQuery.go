@@ -4,6 +4,6 @@ const (
 	FindUserByEmailQuery = `
 		SELECT id, password_hash
 		FROM users
-		WHERE email = $1
+		WHERE email_canonical = lower(normalize($1, NFKC))
 	`
 )
@@ -4,6 +4,6 @@ const (
 	FindUserByUsernameQuery = `
 		SELECT id, password_hash
 		FROM users
-		WHERE username = $1
+		WHERE username_canonical = lower(normalize($1, NFKC))
 	`
 )
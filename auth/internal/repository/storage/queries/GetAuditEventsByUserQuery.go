@@ -0,0 +1,11 @@
+package storage
+
+// GetAuditEventsByUserQuery returns the events about the user. Events the
+// user caused for somebody else belong to that person.
+const (
+	GetAuditEventsByUserQuery = `
+	SELECT id, actor_id, subject_id, action, details, ip, created_at
+	FROM audit_events
+	WHERE subject_id = $1
+	ORDER BY created_at`
+)
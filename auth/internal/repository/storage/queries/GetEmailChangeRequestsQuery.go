@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetEmailChangeRequestsQuery = `
+	SELECT old_email, new_email, created_at, expires_at, confirmed_at, cancelled_at
+	FROM email_change_requests
+	WHERE user_id = $1
+	ORDER BY created_at`
+)
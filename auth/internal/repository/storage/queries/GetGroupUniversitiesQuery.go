@@ -0,0 +1,12 @@
+package storage
+
+const (
+	// GetGroupUniversitiesQuery infers the university of a group from its
+	// students, groups are not stored on their own. Two rows are enough to
+	// tell that the students disagree.
+	GetGroupUniversitiesQuery = `
+	SELECT DISTINCT university_id::text
+	FROM students
+	WHERE group_id = $1 AND university_id IS NOT NULL
+	LIMIT 2`
+)
@@ -0,0 +1,13 @@
+package storage
+
+const (
+	// GetIdentityConflictsQuery lists conflicts that are still open, i.e. the
+	// user has not changed the colliding value yet.
+	GetIdentityConflictsQuery = `
+	SELECT c.id, c.user_id, c.field, c.value, c.kept_user_id, c.detected_at
+	FROM identity_conflicts c
+	JOIN users u ON u.id = c.user_id
+	WHERE (c.field = 'username' AND u.username_canonical IS NULL)
+	   OR (c.field = 'email' AND u.email_canonical IS NULL)
+	ORDER BY c.detected_at, c.user_id`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetKeyIssuancesQuery = `
+	SELECT key_id, role, attributes, issued_at
+	FROM activation_key_issuances
+	WHERE issued_by = $1
+	ORDER BY issued_at`
+)
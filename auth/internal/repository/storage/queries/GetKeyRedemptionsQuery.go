@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetKeyRedemptionsQuery = `
+	SELECT role, key_id, attributes, redeemed_at
+	FROM activation_key_redemptions
+	WHERE user_id = $1
+	ORDER BY redeemed_at`
+)
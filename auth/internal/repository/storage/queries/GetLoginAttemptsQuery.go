@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetLoginAttemptsQuery = `
+	SELECT user_id, login, method, succeeded, COALESCE(ip, ''), COALESCE(user_agent, ''), created_at
+	FROM login_attempts
+	WHERE user_id = $1
+	ORDER BY created_at`
+)
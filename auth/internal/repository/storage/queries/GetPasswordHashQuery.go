@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetPasswordHashQuery = `
+		SELECT password_hash
+		FROM users
+		WHERE id = $1
+	`
+)
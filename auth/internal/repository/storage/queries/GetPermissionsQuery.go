@@ -0,0 +1,8 @@
+package storage
+
+const (
+	GetPermissionsQuery = `
+	SELECT name, description
+	FROM permissions
+	ORDER BY name`
+)
@@ -0,0 +1,10 @@
+package storage
+
+const (
+	GetRoleAssignmentsQuery = `
+	SELECT ur.user_id, r.name, ur.scope_type, ur.scope_id, ur.granted_at, ur.granted_by::text, ur.expires_at
+	FROM user_roles ur
+	JOIN roles r ON ur.role_id = r.id
+	WHERE ur.user_id = $1 AND (ur.expires_at IS NULL OR ur.expires_at > now())
+	ORDER BY r.name, ur.scope_type, ur.scope_id`
+)
@@ -0,0 +1,12 @@
+package storage
+
+const (
+	GetRoleMembersQuery = `
+	SELECT u.id, u.username, u.email, u.deactivated_at IS NULL AND u.deleted_at IS NULL AND u.erased_at IS NULL,
+		ur.granted_at, ur.granted_by::text, ur.expires_at
+	FROM user_roles ur
+	JOIN roles r ON ur.role_id = r.id
+	JOIN users u ON ur.user_id = u.id
+	WHERE r.name = $1 AND ur.scope_type = 'global' AND (ur.expires_at IS NULL OR ur.expires_at > now())
+	ORDER BY u.username`
+)
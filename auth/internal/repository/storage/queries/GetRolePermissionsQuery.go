@@ -0,0 +1,11 @@
+package storage
+
+const (
+	GetRolePermissionsQuery = `
+	SELECT p.name
+	FROM role_permissions rp
+	JOIN roles r ON r.id = rp.role_id
+	JOIN permissions p ON p.id = rp.permission_id
+	WHERE r.name = $1
+	ORDER BY p.name`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetRoleRequestQuery = `
+	SELECT id, user_id, role, university_id, group_id::text, enrollment_year, degree, comment, status,
+	reviewed_by::text, review_comment, reviewed_at, created_at
+	FROM role_requests
+	WHERE id = $1`
+)
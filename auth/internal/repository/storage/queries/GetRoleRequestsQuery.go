@@ -0,0 +1,10 @@
+package storage
+
+const (
+	GetRoleRequestsQuery = `
+	SELECT id, user_id, role, university_id, group_id::text, enrollment_year, degree, comment, status,
+	reviewed_by::text, review_comment, reviewed_at, created_at
+	FROM role_requests
+	WHERE ($1 = '' OR status = $1) AND ($2 = '' OR university_id::text = $2)
+	ORDER BY created_at`
+)
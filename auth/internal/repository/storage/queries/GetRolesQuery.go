@@ -0,0 +1,12 @@
+package storage
+
+const (
+	GetRolesQuery = `
+	SELECT r.name, r.description,
+	       COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+	FROM roles r
+	LEFT JOIN role_permissions rp ON rp.role_id = r.id
+	LEFT JOIN permissions p ON p.id = rp.permission_id
+	GROUP BY r.id
+	ORDER BY r.name`
+)
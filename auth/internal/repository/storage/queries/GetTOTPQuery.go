@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetTOTPQuery = `
+		SELECT user_id, secret, enabled, last_used_step
+		FROM user_totp
+		WHERE user_id = $1
+	`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetTeacherGroupsQuery = `
+	SELECT group_id::text
+	FROM teacher_groups
+	WHERE user_id = $1
+	ORDER BY group_id`
+)
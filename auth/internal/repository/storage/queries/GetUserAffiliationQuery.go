@@ -0,0 +1,14 @@
+package storage
+
+const (
+	// GetUserAffiliationQuery returns the university and group a user belongs
+	// to, taken from their student or teacher profile first.
+	GetUserAffiliationQuery = `
+	SELECT COALESCE(s.university_id::text, t.university_id::text, u.university_id::text, ''),
+	       COALESCE(s.group_id::text, '')
+	FROM users u
+	LEFT JOIN students s ON s.user_id = u.id
+	LEFT JOIN teachers t ON t.user_id = u.id
+	WHERE u.id = $1 AND u.deleted_at IS NULL
+	LIMIT 1`
+)
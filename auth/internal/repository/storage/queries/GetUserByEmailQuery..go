@@ -2,7 +2,7 @@ package storage
 
 const (
 	GetUserByEmailQuery = `
-	SELECT id, username, email, first_name, last_name, created_at
+	SELECT id, username, email, first_name, last_name, created_at, COALESCE(university_id::text, '')
 	FROM users
-	WHERE email = $1`
+	WHERE email_canonical = lower(normalize($1, NFKC))`
 )
@@ -2,7 +2,7 @@ package storage
 
 const (
 	GetUserByIDQuery = `
-	SELECT id, username, email, first_name, last_name, created_at
+	SELECT id, username, email, first_name, last_name, created_at, COALESCE(university_id::text, '')
 	FROM users
 	WHERE id = $1`
 )
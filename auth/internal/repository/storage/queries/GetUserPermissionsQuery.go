@@ -0,0 +1,11 @@
+package storage
+
+const (
+	GetUserPermissionsQuery = `
+	SELECT DISTINCT p.name, ur.scope_type, ur.scope_id
+	FROM user_roles ur
+	JOIN role_permissions rp ON rp.role_id = ur.role_id
+	JOIN permissions p ON p.id = rp.permission_id
+	WHERE ur.user_id = $1 AND (ur.expires_at IS NULL OR ur.expires_at > now())
+	ORDER BY p.name, ur.scope_type, ur.scope_id`
+)
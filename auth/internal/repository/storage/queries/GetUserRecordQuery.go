@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetUserRecordQuery = `
+	SELECT id, username, email, COALESCE(first_name, ''), COALESCE(last_name, ''), created_at, COALESCE(university_id::text, ''),
+	       sessions_revoked_at, deactivated_at, deleted_at
+	FROM users
+	WHERE id = $1`
+)
@@ -0,0 +1,10 @@
+package storage
+
+const (
+	GetUserRoleRequestsQuery = `
+	SELECT id, user_id, role, university_id, group_id::text, enrollment_year, degree, comment, status,
+	reviewed_by::text, review_comment, reviewed_at, created_at
+	FROM role_requests
+	WHERE user_id = $1
+	ORDER BY created_at DESC`
+)
@@ -2,9 +2,9 @@ package storage
 
 const (
 	GetUserRolesQuery = `
-		SELECT r.name
+		SELECT DISTINCT r.name
 		FROM user_roles ur
 		JOIN roles r ON ur.role_id = r.id
-		WHERE ur.user_id = $1
+		WHERE ur.user_id = $1 AND (ur.expires_at IS NULL OR ur.expires_at > now())
 	`
 )
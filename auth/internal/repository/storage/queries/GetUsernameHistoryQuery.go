@@ -0,0 +1,9 @@
+package storage
+
+const (
+	GetUsernameHistoryQuery = `
+	SELECT username, changed_at, reserved_until
+	FROM username_history
+	WHERE user_id = $1
+	ORDER BY changed_at DESC`
+)
@@ -0,0 +1,10 @@
+package storage
+
+const (
+	GetUsersToEraseQuery = `
+	SELECT id
+	FROM users
+	WHERE deleted_at IS NOT NULL AND deleted_at < $1 AND erased_at IS NULL
+	ORDER BY deleted_at
+	LIMIT $2`
+)
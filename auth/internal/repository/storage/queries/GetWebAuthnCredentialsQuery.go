@@ -0,0 +1,10 @@
+package storage
+
+const (
+	GetWebAuthnCredentialsQuery = `
+		SELECT id, user_id, credential_id, name, credential, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+)
@@ -0,0 +1,10 @@
+package storage
+
+const (
+	GrantPermissionQuery = `
+	INSERT INTO role_permissions (role_id, permission_id)
+	SELECT r.id, p.id
+	FROM roles r, permissions p
+	WHERE r.name = $1 AND p.name = $2
+	ON CONFLICT DO NOTHING`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	GrantRoleQuery = `
+	INSERT INTO user_roles (user_id, role_id, scope_type, scope_id, granted_by, expires_at)
+	SELECT $1, id, $3, $4, $5, $6 FROM roles WHERE name = $2
+	ON CONFLICT DO NOTHING`
+)
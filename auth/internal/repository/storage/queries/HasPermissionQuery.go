@@ -0,0 +1,20 @@
+package storage
+
+const (
+	// HasPermissionQuery matches global assignments, and scoped ones when the
+	// resource lies in their university ($3) or group ($4). Pass empty strings
+	// to only consider global assignments. Expired assignments that the
+	// sweeper has not removed yet never match.
+	HasPermissionQuery = `
+	SELECT EXISTS(
+		SELECT 1
+		FROM user_roles ur
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE ur.user_id = $1 AND p.name = $2
+		  AND (ur.expires_at IS NULL OR ur.expires_at > now())
+		  AND (ur.scope_type = 'global'
+		    OR (ur.scope_type = 'university' AND ur.scope_id = $3 AND $3 <> '')
+		    OR (ur.scope_type = 'group' AND ur.scope_id = $4 AND $4 <> ''))
+	)`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	IncrementEmailLoginAttemptsQuery = `
+		UPDATE email_login_tokens
+		SET attempts = attempts + 1
+		WHERE user_id = $1 AND method = 'code' AND used_at IS NULL AND expires_at > now()
+	`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	IsUserActiveQuery = `
+	SELECT deactivated_at IS NULL AND deleted_at IS NULL AND erased_at IS NULL
+	FROM users
+	WHERE id = $1`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	IsUsernameReservedQuery = `
+	SELECT EXISTS(
+		SELECT 1 FROM username_history
+		WHERE username_canonical = lower(normalize($1, NFKC)) AND reserved_until > now() AND user_id::text <> $2
+	)`
+)
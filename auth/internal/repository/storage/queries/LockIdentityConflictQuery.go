@@ -0,0 +1,14 @@
+package storage
+
+const (
+	// LockIdentityConflictQuery locks the user of an open conflict, so that
+	// two admins can not resolve it at the same time.
+	LockIdentityConflictQuery = `
+	SELECT c.id, c.user_id, c.field, c.value, c.kept_user_id, c.detected_at
+	FROM identity_conflicts c
+	JOIN users u ON u.id = c.user_id
+	WHERE c.id = $1
+	  AND ((c.field = 'username' AND u.username_canonical IS NULL)
+	    OR (c.field = 'email' AND u.email_canonical IS NULL))
+	FOR UPDATE OF u`
+)
@@ -0,0 +1,10 @@
+package storage
+
+const (
+	// LockKeyIssuerQuery serializes the quota checks of one issuer.
+	LockKeyIssuerQuery = `
+	SELECT id
+	FROM users
+	WHERE id = $1
+	FOR UPDATE`
+)
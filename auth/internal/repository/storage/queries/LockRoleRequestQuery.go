@@ -0,0 +1,12 @@
+package storage
+
+const (
+	// LockRoleRequestQuery locks a pending request until it is reviewed in
+	// the same transaction.
+	LockRoleRequestQuery = `
+	SELECT id, user_id, role, university_id, group_id::text, enrollment_year, degree, comment, status,
+	reviewed_by::text, review_comment, reviewed_at, created_at
+	FROM role_requests
+	WHERE id = $1 AND status = 'pending'
+	FOR UPDATE`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	LockUsernameQuery = `
+	SELECT username
+	FROM users
+	WHERE id = $1
+	FOR UPDATE`
+)
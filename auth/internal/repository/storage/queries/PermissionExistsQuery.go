@@ -0,0 +1,6 @@
+package storage
+
+const (
+	PermissionExistsQuery = `
+	SELECT EXISTS(SELECT 1 FROM permissions WHERE name = $1)`
+)
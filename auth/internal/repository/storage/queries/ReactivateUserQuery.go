@@ -0,0 +1,9 @@
+package storage
+
+const (
+	ReactivateUserQuery = `
+	UPDATE users
+	SET deactivated_at = NULL,
+	    deleted_at = NULL
+	WHERE id = $1 AND erased_at IS NULL`
+)
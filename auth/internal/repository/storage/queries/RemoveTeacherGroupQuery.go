@@ -0,0 +1,5 @@
+package storage
+
+const (
+	RemoveTeacherGroupQuery = `DELETE FROM teacher_groups WHERE user_id = $1 AND group_id = $2`
+)
@@ -0,0 +1,10 @@
+package storage
+
+const (
+	ReviewRoleRequestQuery = `
+	UPDATE role_requests
+	SET status = $2, reviewed_by = $3, review_comment = $4, reviewed_at = now()
+	WHERE id = $1 AND status = 'pending'
+	RETURNING id, user_id, role, university_id, group_id::text, enrollment_year, degree, comment, status,
+	reviewed_by::text, review_comment, reviewed_at, created_at`
+)
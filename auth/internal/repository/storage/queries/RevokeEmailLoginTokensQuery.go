@@ -0,0 +1,9 @@
+package storage
+
+const (
+	RevokeEmailLoginTokensQuery = `
+		UPDATE email_login_tokens
+		SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+)
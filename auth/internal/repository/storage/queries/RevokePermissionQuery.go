@@ -0,0 +1,9 @@
+package storage
+
+const (
+	RevokePermissionQuery = `
+	DELETE FROM role_permissions rp
+	USING roles r, permissions p
+	WHERE rp.role_id = r.id AND rp.permission_id = p.id
+	  AND r.name = $1 AND p.name = $2`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	RevokeSessionsQuery = `
+	UPDATE users
+	SET sessions_revoked_at = now()
+	WHERE id = $1`
+)
@@ -0,0 +1,12 @@
+package storage
+
+const (
+	// RevokeUserRoleQuery removes the assignment of the role in one scope, or
+	// in every scope when the scope type ($3) is empty.
+	RevokeUserRoleQuery = `
+	DELETE FROM user_roles ur
+	USING roles r
+	WHERE ur.role_id = r.id AND ur.user_id = $1 AND r.name = $2
+	  AND ($3 = '' OR (ur.scope_type = $3 AND ur.scope_id = $4))
+	RETURNING ur.user_id, r.name, ur.scope_type, ur.scope_id, ur.granted_at, ur.granted_by::text, ur.expires_at`
+)
@@ -0,0 +1,6 @@
+package storage
+
+const (
+	RoleExistsQuery = `
+	SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)`
+)
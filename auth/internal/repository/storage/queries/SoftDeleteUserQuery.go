@@ -0,0 +1,9 @@
+package storage
+
+const (
+	SoftDeleteUserQuery = `
+	UPDATE users
+	SET deleted_at = COALESCE(deleted_at, now()),
+	    sessions_revoked_at = now()
+	WHERE id = $1 AND erased_at IS NULL`
+)
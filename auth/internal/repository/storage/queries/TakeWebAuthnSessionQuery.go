@@ -0,0 +1,9 @@
+package storage
+
+const (
+	TakeWebAuthnSessionQuery = `
+		DELETE FROM webauthn_sessions
+		WHERE id = $1 AND ceremony = $2 AND expires_at > now()
+		RETURNING id, user_id, ceremony, data
+	`
+)
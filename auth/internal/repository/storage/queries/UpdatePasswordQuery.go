@@ -0,0 +1,8 @@
+package storage
+
+const (
+	UpdatePasswordQuery = `
+	UPDATE users
+	SET password_hash = $2
+	WHERE id = $1 AND deleted_at IS NULL AND erased_at IS NULL`
+)
@@ -0,0 +1,8 @@
+package storage
+
+const (
+	UpdateTeacherQuery = `
+	UPDATE teachers
+	SET degree = COALESCE($2, degree)
+	WHERE user_id = $1`
+)
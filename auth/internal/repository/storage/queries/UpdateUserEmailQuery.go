@@ -0,0 +1,9 @@
+package storage
+
+const (
+	UpdateUserEmailQuery = `
+	UPDATE users
+	SET email = $2,
+	    email_canonical = lower(normalize($2, NFKC))
+	WHERE id = $1`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	UpdateUserQuery = `
+	UPDATE users
+	SET first_name = COALESCE($2, first_name),
+	    last_name = COALESCE($3, last_name)
+	WHERE id = $1`
+)
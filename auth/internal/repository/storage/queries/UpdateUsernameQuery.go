@@ -0,0 +1,9 @@
+package storage
+
+const (
+	UpdateUsernameQuery = `
+	UPDATE users
+	SET username = $2,
+	    username_canonical = lower(normalize($2, NFKC))
+	WHERE id = $1`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	UpdateWebAuthnCredentialQuery = `
+		UPDATE webauthn_credentials
+		SET credential = $2, last_used_at = now()
+		WHERE credential_id = $1
+	`
+)
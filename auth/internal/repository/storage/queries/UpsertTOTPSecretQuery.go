@@ -0,0 +1,11 @@
+package storage
+
+const (
+	UpsertTOTPSecretQuery = `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = now()
+		WHERE user_totp.enabled = false
+	`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	UseRecoveryCodeQuery = `
+		UPDATE mfa_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+)
@@ -0,0 +1,9 @@
+package storage
+
+const (
+	UseTOTPStepQuery = `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+)
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Role requests...
+
+// CreateRoleRequest returns sql.ErrNoRows when the user already has a
+// pending request for the role.
+func (s *DBStorage) CreateRoleRequest(ctx context.Context, request models.RoleRequest) (models.RoleRequest, error) {
+	return scanRoleRequest(s.db.QueryRowContext(ctx, storage.CreateRoleRequestQuery,
+		request.UserID, request.Role, request.UniversityID, request.GroupID,
+		request.EnrollmentYear, request.Degree, request.Comment))
+}
+
+func (s *DBStorage) GetRoleRequest(ctx context.Context, requestID string) (models.RoleRequest, error) {
+	return scanRoleRequest(s.db.QueryRowContext(ctx, storage.GetRoleRequestQuery, requestID))
+}
+
+// GetRoleRequests lists requests, optionally filtered by status and
+// university. Empty filters match everything.
+func (s *DBStorage) GetRoleRequests(ctx context.Context, status, universityID string) ([]models.RoleRequest, error) {
+	return scanRoleRequests(s.db.QueryContext(ctx, storage.GetRoleRequestsQuery, status, universityID))
+}
+
+func (s *DBStorage) GetUserRoleRequests(ctx context.Context, userID string) ([]models.RoleRequest, error) {
+	return scanRoleRequests(s.db.QueryContext(ctx, storage.GetUserRoleRequestsQuery, userID))
+}
+
+// ReviewRoleRequest returns sql.ErrNoRows when the request is not pending.
+func (s *DBStorage) ReviewRoleRequest(ctx context.Context, review models.RoleReview) (models.RoleRequest, error) {
+	return scanRoleRequest(s.db.QueryRowContext(ctx, storage.ReviewRoleRequestQuery,
+		review.RequestID, review.Status, review.ReviewedBy, review.Comment))
+}
+
+// Role requests (transactions)...
+
+// LockRoleRequest returns sql.ErrNoRows when the request is not pending.
+func (s *storageTx) LockRoleRequest(ctx context.Context, requestID string) (models.RoleRequest, error) {
+	return scanRoleRequest(s.tx.QueryRowContext(ctx, storage.LockRoleRequestQuery, requestID))
+}
+
+func (s *storageTx) ReviewRoleRequest(ctx context.Context, review models.RoleReview) (models.RoleRequest, error) {
+	return scanRoleRequest(s.tx.QueryRowContext(ctx, storage.ReviewRoleRequestQuery,
+		review.RequestID, review.Status, review.ReviewedBy, review.Comment))
+}
+
+func scanRoleRequest(row *sql.Row) (models.RoleRequest, error) {
+	var request models.RoleRequest
+	err := row.Scan(&request.ID, &request.UserID, &request.Role, &request.UniversityID, &request.GroupID,
+		&request.EnrollmentYear, &request.Degree, &request.Comment, &request.Status,
+		&request.ReviewedBy, &request.ReviewComment, &request.ReviewedAt, &request.CreatedAt)
+	return request, err
+}
+
+func scanRoleRequests(rows *sql.Rows, err error) ([]models.RoleRequest, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.RoleRequest
+	for rows.Next() {
+		var request models.RoleRequest
+		if err := rows.Scan(&request.ID, &request.UserID, &request.Role, &request.UniversityID, &request.GroupID,
+			&request.EnrollmentYear, &request.Degree, &request.Comment, &request.Status,
+			&request.ReviewedBy, &request.ReviewComment, &request.ReviewedAt, &request.CreatedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, rows.Err()
+}
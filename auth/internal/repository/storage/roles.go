@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Roles and permissions...
+
+func (s *DBStorage) GetRoles(ctx context.Context) ([]models.Role, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetRolesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.Name, &role.Description, pq.Array(&role.Permissions)); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (s *DBStorage) GetPermissions(ctx context.Context) ([]models.Permission, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetPermissionsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []models.Permission
+	for rows.Next() {
+		var permission models.Permission
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+func (s *DBStorage) RoleExists(ctx context.Context, role string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, storage.RoleExistsQuery, role).Scan(&exists)
+	return exists, err
+}
+
+func (s *DBStorage) PermissionExists(ctx context.Context, permission string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, storage.PermissionExistsQuery, permission).Scan(&exists)
+	return exists, err
+}
+
+// CreateRole reports false when a role with the name already exists.
+func (s *DBStorage) CreateRole(ctx context.Context, role, description string) (bool, error) {
+	_, err := s.db.ExecContext(ctx, storage.CreateRoleQuery, role, description)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *DBStorage) DeleteRole(ctx context.Context, role string) (bool, error) {
+	return execAffected(s.db.ExecContext(ctx, storage.DeleteRoleQuery, role))
+}
+
+func (s *DBStorage) GrantPermission(ctx context.Context, role, permission string) error {
+	_, err := s.db.ExecContext(ctx, storage.GrantPermissionQuery, role, permission)
+	return err
+}
+
+func (s *DBStorage) RevokePermission(ctx context.Context, role, permission string) (bool, error) {
+	return execAffected(s.db.ExecContext(ctx, storage.RevokePermissionQuery, role, permission))
+}
+
+func (s *DBStorage) GetUserPermissions(ctx context.Context, userID string) ([]models.ScopedPermission, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetUserPermissionsQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []models.ScopedPermission
+	for rows.Next() {
+		var permission models.ScopedPermission
+		if err := rows.Scan(&permission.Permission, &permission.Type, &permission.ID); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// HasPermission checks the permission for a resource in the given university
+// and group. Empty IDs only match global role assignments.
+func (s *DBStorage) HasPermission(ctx context.Context, userID, permission, universityID, groupID string) (bool, error) {
+	var granted bool
+	err := s.db.QueryRowContext(ctx, storage.HasPermissionQuery, userID, permission, universityID, groupID).Scan(&granted)
+	return granted, err
+}
+
+// Role assignments...
+
+func (s *DBStorage) GetRoleAssignments(ctx context.Context, userID string) ([]models.RoleAssignment, error) {
+	return scanRoleAssignments(s.db.QueryContext(ctx, storage.GetRoleAssignmentsQuery, userID))
+}
+
+// GrantRole reports false when the user already has the role in that scope.
+func (s *DBStorage) GrantRole(ctx context.Context, grant models.RoleGrant) (bool, error) {
+	return execAffected(s.db.ExecContext(ctx, storage.GrantRoleQuery,
+		grant.UserID, grant.Role, grant.Type, grant.ID, grant.GrantedBy, grant.ExpiresAt))
+}
+
+// GetRoleMembers lists the users holding the role globally.
+func (s *DBStorage) GetRoleMembers(ctx context.Context, role string) ([]models.RoleMember, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetRoleMembersQuery, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.RoleMember
+	for rows.Next() {
+		var member models.RoleMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Email, &member.Active,
+			&member.GrantedAt, &member.GrantedBy, &member.ExpiresAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// Role assignments (transactions)...
+
+// RevokeUserRole removes the role in the scope, or in all scopes when scope is
+// nil, and returns the removed assignments.
+func (s *storageTx) RevokeUserRole(ctx context.Context, userID, role string, scope *models.RoleScope) ([]models.RoleAssignment, error) {
+	var scopeType, scopeID string
+	if scope != nil {
+		scopeType, scopeID = scope.Type, scope.ID
+	}
+	return scanRoleAssignments(s.tx.QueryContext(ctx, storage.RevokeUserRoleQuery, userID, role, scopeType, scopeID))
+}
+
+// ExpireUserRoles removes up to limit assignments that expired before now and
+// returns them.
+func (s *storageTx) ExpireUserRoles(ctx context.Context, now time.Time, limit int) ([]models.RoleAssignment, error) {
+	return scanRoleAssignments(s.tx.QueryContext(ctx, storage.ExpireUserRolesQuery, now, limit))
+}
+
+func (s *storageTx) DeleteStudent(ctx context.Context, userID string) error {
+	_, err := s.tx.ExecContext(ctx, storage.DeleteStudentQuery, userID)
+	return err
+}
+
+func (s *storageTx) DeleteTeacher(ctx context.Context, userID string) error {
+	_, err := s.tx.ExecContext(ctx, storage.DeleteTeacherQuery, userID)
+	return err
+}
+
+func scanRoleAssignments(rows *sql.Rows, err error) ([]models.RoleAssignment, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []models.RoleAssignment
+	for rows.Next() {
+		var assignment models.RoleAssignment
+		if err := rows.Scan(&assignment.UserID, &assignment.Role, &assignment.Type, &assignment.ID,
+			&assignment.GrantedAt, &assignment.GrantedBy, &assignment.ExpiresAt); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	return assignments, rows.Err()
+}
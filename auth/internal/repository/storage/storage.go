@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
 	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
@@ -15,10 +16,8 @@ type Storage interface {
 	FindUserByUsername(ctx context.Context, username string) (string, string, error)
 	FindUserByEmail(ctx context.Context, email string) (string, string, error)
 	FindUserByID(ctx context.Context, userID string) (bool, error)
+	GetPasswordHash(ctx context.Context, userID string) (string, error)
 
-	CreateStudent(ctx context.Context, userID, groupID, universityID string, enrollmentYear int) error
-	CreateTeacher(ctx context.Context, userID, universityID, degree string) error
-	AddUserRole(ctx context.Context, userID, role string) error
 	CheckUserRole(ctx context.Context, userID, role string) (bool, error)
 
 	GetUserByID(ctx context.Context, userID string) (models.User, error)
@@ -29,6 +28,84 @@ type Storage interface {
 	GetTeachersByUni(ctx context.Context, uniID string) ([]models.Teacher, error)
 	GetUserRoles(ctx context.Context, userID string) ([]string, error)
 
+	CreateLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error
+	CountLoginFailures(ctx context.Context, userID string, since time.Time) (int, error)
+	CountLoginFailuresByMethod(ctx context.Context, userID string, methods []string, since time.Time) (int, error)
+	CreateEmailLoginToken(ctx context.Context, userID, method, secretHash string, expiresAt time.Time) error
+	RevokeEmailLoginTokens(ctx context.Context, userID string) error
+	ConsumeEmailLoginLink(ctx context.Context, secretHash string) (string, error)
+	ConsumeEmailLoginCode(ctx context.Context, userID, secretHash string, maxAttempts int) (bool, error)
+	IncrementEmailLoginAttempts(ctx context.Context, userID string) error
+
+	CreateEmailChangeRequest(ctx context.Context, userID, oldEmail, newEmail, confirmHash, cancelHash string, expiresAt time.Time) error
+	CancelPendingEmailChanges(ctx context.Context, userID string) error
+	CancelEmailChange(ctx context.Context, cancelHash string) (string, error)
+	RevokeSessions(ctx context.Context, userID string) error
+	IsUsernameReserved(ctx context.Context, username, userID string) (bool, error)
+	GetUsernameHistory(ctx context.Context, userID string) ([]models.UsernameHistory, error)
+	GetIdentityConflicts(ctx context.Context) ([]models.IdentityConflict, error)
+	GetIdentityConflict(ctx context.Context, conflictID string) (models.IdentityConflict, error)
+	GetSessionState(ctx context.Context, userID string) (models.SessionState, error)
+
+	IsUserActive(ctx context.Context, userID string) (bool, error)
+	DeactivateUser(ctx context.Context, userID string) (bool, error)
+	SoftDeleteUser(ctx context.Context, userID string) (bool, error)
+	ReactivateUser(ctx context.Context, userID string) (bool, error)
+	GetUsersToErase(ctx context.Context, deletedBefore time.Time, limit int) ([]string, error)
+
+	CreateAuditEvent(ctx context.Context, event models.AuditEvent) error
+	GetAuditEventsByUser(ctx context.Context, userID string) ([]models.AuditEvent, error)
+	CreateKeyIssuance(ctx context.Context, issuance models.KeyIssuance) error
+	GetKeyIssuances(ctx context.Context, issuedBy string) ([]models.KeyIssuance, error)
+	GetKeyRedemptions(ctx context.Context, userID string) ([]models.KeyRedemption, error)
+
+	GetUserRecord(ctx context.Context, userID string) (models.UserRecord, error)
+	GetLoginAttempts(ctx context.Context, userID string) ([]models.LoginAttempt, error)
+	GetEmailChangeRequests(ctx context.Context, userID string) ([]models.EmailChangeRequest, error)
+
+	GetRoles(ctx context.Context) ([]models.Role, error)
+	GetPermissions(ctx context.Context) ([]models.Permission, error)
+	RoleExists(ctx context.Context, role string) (bool, error)
+	PermissionExists(ctx context.Context, permission string) (bool, error)
+	CreateRole(ctx context.Context, role, description string) (bool, error)
+	DeleteRole(ctx context.Context, role string) (bool, error)
+	GrantPermission(ctx context.Context, role, permission string) error
+	RevokePermission(ctx context.Context, role, permission string) (bool, error)
+	GetUserPermissions(ctx context.Context, userID string) ([]models.ScopedPermission, error)
+	HasPermission(ctx context.Context, userID, permission, universityID, groupID string) (bool, error)
+	GetRoleAssignments(ctx context.Context, userID string) ([]models.RoleAssignment, error)
+	GrantRole(ctx context.Context, grant models.RoleGrant) (bool, error)
+	GetRoleMembers(ctx context.Context, role string) ([]models.RoleMember, error)
+
+	GetTeacherGroups(ctx context.Context, userID string) ([]string, error)
+	AddTeacherGroup(ctx context.Context, userID, groupID string) (bool, error)
+	RemoveTeacherGroup(ctx context.Context, userID, groupID string) (bool, error)
+	GetUserAffiliation(ctx context.Context, userID string) (models.Affiliation, error)
+	GetGroupUniversities(ctx context.Context, groupID string) ([]string, error)
+	GetRolePermissions(ctx context.Context, role string) ([]string, error)
+
+	CreateRoleRequest(ctx context.Context, request models.RoleRequest) (models.RoleRequest, error)
+	GetRoleRequest(ctx context.Context, requestID string) (models.RoleRequest, error)
+	GetRoleRequests(ctx context.Context, status, universityID string) ([]models.RoleRequest, error)
+	GetUserRoleRequests(ctx context.Context, userID string) ([]models.RoleRequest, error)
+	ReviewRoleRequest(ctx context.Context, review models.RoleReview) (models.RoleRequest, error)
+
+	UpsertTOTPSecret(ctx context.Context, userID, secret string) (bool, error)
+	GetTOTP(ctx context.Context, userID string) (models.TOTP, error)
+	EnableTOTP(ctx context.Context, userID string, step int64) (bool, error)
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID string) error
+	DeleteRecoveryCodes(ctx context.Context, userID string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+
+	CreateWebAuthnCredential(ctx context.Context, userID string, credentialID []byte, name string, credential []byte) error
+	GetWebAuthnCredentials(ctx context.Context, userID string) ([]models.WebAuthnCredential, error)
+	UpdateWebAuthnCredential(ctx context.Context, credentialID []byte, credential []byte) error
+	DeleteWebAuthnCredential(ctx context.Context, userID, id string) (bool, error)
+	CreateWebAuthnSession(ctx context.Context, userID sql.NullString, ceremony string, data []byte, expiresAt time.Time) (string, error)
+	TakeWebAuthnSession(ctx context.Context, sessionID, ceremony string) (models.WebAuthnSession, error)
+
 	BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error)
 }
 
@@ -39,8 +116,34 @@ type Tx interface {
 	FindUserByEmail(ctx context.Context, email string) (string, string, error)
 	CreateStudent(ctx context.Context, userID, groupID, universityID string, enrollmentYear int) error
 	CreateTeacher(ctx context.Context, userID, universityID, degree string) error
-	AddUserRole(ctx context.Context, userID, role string) error
+	AddUserRole(ctx context.Context, userID, role string, scope models.RoleScope) error
 	CheckUserRole(ctx context.Context, userID, role string) (bool, error)
+	RevokeUserRole(ctx context.Context, userID, role string, scope *models.RoleScope) ([]models.RoleAssignment, error)
+	ExpireUserRoles(ctx context.Context, now time.Time, limit int) ([]models.RoleAssignment, error)
+	DeleteStudent(ctx context.Context, userID string) error
+	DeleteTeacher(ctx context.Context, userID string) error
+	CreateRecoveryCode(ctx context.Context, userID, codeHash string) error
+	DeleteRecoveryCodes(ctx context.Context, userID string) error
+	UpdateUser(ctx context.Context, userID string, firstName, lastName *string) (bool, error)
+	UpdateTeacher(ctx context.Context, userID string, degree *string) (bool, error)
+	ConfirmEmailChange(ctx context.Context, confirmHash string) (models.EmailChange, error)
+	UpdateUserEmail(ctx context.Context, userID, email string) (bool, error)
+	RevokeSessions(ctx context.Context, userID string) error
+	LockUsername(ctx context.Context, userID string) (string, error)
+	IsUsernameReserved(ctx context.Context, username, userID string) (bool, error)
+	UpdateUsername(ctx context.Context, userID, username string) (bool, error)
+	CreateUsernameHistory(ctx context.Context, userID, username string, reservedUntil time.Time) error
+	LockIdentityConflict(ctx context.Context, conflictID string) (models.IdentityConflict, error)
+	CreateKeyRedemption(ctx context.Context, redemption models.KeyRedemption) (bool, error)
+	LockKeyIssuer(ctx context.Context, issuedBy string) error
+	CountKeyIssuances(ctx context.Context, issuedBy string, since time.Time) (int, error)
+	CreateKeyIssuance(ctx context.Context, issuance models.KeyIssuance) error
+	EraseUser(ctx context.Context, userID string) (bool, error)
+	EraseTeacher(ctx context.Context, userID string) error
+	DeleteUserPersonalData(ctx context.Context, userID string) error
+	UpdatePassword(ctx context.Context, userID, passwordHash string) (bool, error)
+	LockRoleRequest(ctx context.Context, requestID string) (models.RoleRequest, error)
+	ReviewRoleRequest(ctx context.Context, review models.RoleReview) (models.RoleRequest, error)
 
 	Commit() error
 	Rollback() error
@@ -81,7 +184,7 @@ func (s *DBStorage) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error
 func (s *DBStorage) CreateUser(ctx context.Context, user models.RegisterUserData) (string, error) {
 	var userID string
 	err := s.db.QueryRowContext(ctx, storage.CreateUserQuery,
-		user.Username, user.Email, user.PasswordHash, user.FirstName, user.LastName).Scan(&userID)
+		user.Username, user.Email, user.PasswordHash, user.FirstName, user.LastName, user.UniversityID).Scan(&userID)
 	return userID, err
 }
 
@@ -103,19 +206,10 @@ func (s *DBStorage) FindUserByID(ctx context.Context, userID string) (bool, erro
 	return exists, err
 }
 
-func (s *DBStorage) CreateStudent(ctx context.Context, userID, groupID, universityID string, enrollmentYear int) error {
-	_, err := s.db.ExecContext(ctx, storage.CreateStudentQuery, userID, groupID, universityID, enrollmentYear)
-	return err
-}
-
-func (s *DBStorage) CreateTeacher(ctx context.Context, userID, universityID, degree string) error {
-	_, err := s.db.ExecContext(ctx, storage.CreateTeacherQuery, userID, universityID, degree)
-	return err
-}
-
-func (s *DBStorage) AddUserRole(ctx context.Context, userID, role string) error {
-	_, err := s.db.ExecContext(ctx, storage.AddUserRoleQuery, userID, role)
-	return err
+func (s *DBStorage) GetPasswordHash(ctx context.Context, userID string) (string, error) {
+	var passwordHash string
+	err := s.db.QueryRowContext(ctx, storage.GetPasswordHashQuery, userID).Scan(&passwordHash)
+	return passwordHash, err
 }
 
 func (s *DBStorage) CheckUserRole(ctx context.Context, userID, role string) (bool, error) {
@@ -127,14 +221,14 @@ func (s *DBStorage) CheckUserRole(ctx context.Context, userID, role string) (boo
 func (s *DBStorage) GetUserByID(ctx context.Context, userID string) (models.User, error) {
 	var user models.User
 	err := s.db.QueryRowContext(ctx, storage.GetUserByIDQuery, userID).Scan(&user.ID, &user.Username, &user.Email,
-		&user.FirstName, &user.LastName, &user.CreatedAt)
+		&user.FirstName, &user.LastName, &user.CreatedAt, &user.UniversityID)
 	return user, err
 }
 
 func (s *DBStorage) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
 	var user models.User
 	err := s.db.QueryRowContext(ctx, storage.GetUserByEmailQuery, email).Scan(&user.ID, &user.Username, &user.Email,
-		&user.FirstName, &user.LastName, &user.CreatedAt)
+		&user.FirstName, &user.LastName, &user.CreatedAt, &user.UniversityID)
 	return user, err
 }
 
@@ -249,7 +343,7 @@ func (s *DBStorage) GetUserRoles(ctx context.Context, userID string) ([]string,
 func (s *storageTx) CreateUser(ctx context.Context, user models.RegisterUserData) (string, error) {
 	var userID string
 	err := s.tx.QueryRowContext(ctx, storage.CreateUserQuery,
-		user.Username, user.Email, user.PasswordHash, user.FirstName, user.LastName).Scan(&userID)
+		user.Username, user.Email, user.PasswordHash, user.FirstName, user.LastName, user.UniversityID).Scan(&userID)
 	return userID, err
 }
 
@@ -275,8 +369,8 @@ func (s *storageTx) CreateTeacher(ctx context.Context, userID, universityID, deg
 	return err
 }
 
-func (s *storageTx) AddUserRole(ctx context.Context, userID, role string) error {
-	_, err := s.tx.ExecContext(ctx, storage.AddUserRoleQuery, userID, role)
+func (s *storageTx) AddUserRole(ctx context.Context, userID, role string, scope models.RoleScope) error {
+	_, err := s.tx.ExecContext(ctx, storage.AddUserRoleQuery, userID, role, scope.Type, scope.ID)
 	return err
 }
 
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+	storage "github.com/vladlim/auth-service-practice/auth/internal/repository/storage/queries"
+)
+
+// Username history...
+
+// IsUsernameReserved reports whether the username is held by the cooldown of
+// another user. Pass an empty userID to check against everybody.
+func (s *DBStorage) IsUsernameReserved(ctx context.Context, username, userID string) (bool, error) {
+	var reserved bool
+	err := s.db.QueryRowContext(ctx, storage.IsUsernameReservedQuery, username, userID).Scan(&reserved)
+	return reserved, err
+}
+
+func (s *DBStorage) GetUsernameHistory(ctx context.Context, userID string) ([]models.UsernameHistory, error) {
+	rows, err := s.db.QueryContext(ctx, storage.GetUsernameHistoryQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.UsernameHistory
+	for rows.Next() {
+		var entry models.UsernameHistory
+		if err := rows.Scan(&entry.Username, &entry.ChangedAt, &entry.ReservedUntil); err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+
+	return history, rows.Err()
+}
+
+// Username history (transactions)...
+
+func (s *storageTx) LockUsername(ctx context.Context, userID string) (string, error) {
+	var username string
+	err := s.tx.QueryRowContext(ctx, storage.LockUsernameQuery, userID).Scan(&username)
+	return username, err
+}
+
+func (s *storageTx) IsUsernameReserved(ctx context.Context, username, userID string) (bool, error) {
+	var reserved bool
+	err := s.tx.QueryRowContext(ctx, storage.IsUsernameReservedQuery, username, userID).Scan(&reserved)
+	return reserved, err
+}
+
+// UpdateUsername reports false when the username is already taken.
+func (s *storageTx) UpdateUsername(ctx context.Context, userID, username string) (bool, error) {
+	_, err := s.tx.ExecContext(ctx, storage.UpdateUsernameQuery, userID, username)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *storageTx) CreateUsernameHistory(ctx context.Context, userID, username string, reservedUntil time.Time) error {
+	_, err := s.tx.ExecContext(ctx, storage.CreateUsernameHistoryQuery, userID, username, reservedUntil)
+	return err
+}
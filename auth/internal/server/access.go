@@ -0,0 +1,103 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
+)
+
+// Read access...
+
+// authorize authenticates the request and decides the action on the resource
+// the way POST /authz/check would, i.e. with scopes, teacher groups,
+// ownership and policies. The returned status code is meant for the error
+// response.
+func (s *Server) authorize(r *http.Request, action, resource string) (*tokens.Claims, int, error) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		return nil, http.StatusUnauthorized, errors.New("invalid token")
+	}
+
+	decision, err := s.authProvider.Authorize(r.Context(), claims.UserID, action, resource)
+	switch {
+	case errors.Is(err, auth.ErrResourceNotFound):
+		kind, _, _ := strings.Cut(resource, ":")
+		return nil, http.StatusNotFound, errors.New(kind + " not found")
+	case errors.Is(err, auth.ErrInvalidResource):
+		return nil, http.StatusBadRequest, errors.New("id must be a uuid")
+	case errors.Is(err, auth.ErrGroupUniversityConflict):
+		return nil, http.StatusForbidden, err
+
+	case err != nil:
+		return nil, http.StatusInternalServerError, errors.New("failed to check permissions")
+	}
+	if !decision.Allowed {
+		return nil, http.StatusForbidden, errors.New("forbidden")
+	}
+
+	if status, err := s.checkEnrollment(r, claims.UserID); err != nil {
+		return nil, status, err
+	}
+
+	return claims, http.StatusOK, nil
+}
+
+// visibility is what the caller may see of a user record. Without the
+// profile, e.g. for classmates in a group listing, only names and the group
+// remain. The email needs the users:read:email permission on the user.
+type visibility struct {
+	profile bool
+	email   bool
+}
+
+// visibilities decides the visibility of each user for the caller, where the
+// profile requires action on the user.
+func (s *Server) visibilities(r *http.Request, viewerID, action string, userIDs []string) (map[string]visibility, error) {
+	checks := make([]auth.AccessCheck, 0, 2*len(userIDs))
+	for _, userID := range userIDs {
+		resource := auth.ResourceUser + ":" + userID
+		checks = append(checks,
+			auth.AccessCheck{Action: action, Resource: resource},
+			auth.AccessCheck{Action: auth.PermUsersReadEmail, Resource: resource})
+	}
+
+	decisions, err := s.authProvider.CheckAccess(r.Context(), viewerID, checks)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]visibility, len(userIDs))
+	for i, userID := range userIDs {
+		result[userID] = visibility{profile: decisions[2*i].Allowed, email: decisions[2*i+1].Allowed}
+	}
+	return result, nil
+}
+
+func (v visibility) user(user auth.User) auth.User {
+	if !v.email {
+		user.Email = ""
+	}
+	if !v.profile {
+		user.CreatedAt, user.UniversityID = "", ""
+	}
+	return user
+}
+
+func (v visibility) student(student auth.Student) auth.Student {
+	student.User = v.user(student.User)
+	if !v.profile {
+		student.UniversityID, student.EnrollmentYear = "", 0
+	}
+	return student
+}
+
+func (v visibility) teacher(teacher auth.Teacher) auth.Teacher {
+	teacher.User = v.user(teacher.User)
+	if !v.profile {
+		teacher.UniversityID, teacher.Degree = "", ""
+	}
+	return teacher
+}
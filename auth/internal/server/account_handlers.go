@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+)
+
+// Self-service...
+
+func (s *Server) deactivateMeHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	if err := s.authProvider.DeactivateUser(r.Context(), claims.UserID); err != nil {
+		s.respondWithAccountError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, claims.UserID, auth.AuditAccountDeactivated, nil)
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deactivated"})
+}
+
+func (s *Server) deleteMeHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var req DeleteAccountData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		s.respondWithError(w, http.StatusBadRequest, "password is required")
+		return
+	}
+
+	if err := s.authProvider.DeleteOwnAccount(r.Context(), claims.UserID, req.Password); err != nil {
+		s.respondWithAccountError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, claims.UserID, auth.AuditAccountDeleted, nil)
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// Admin...
+
+func (s *Server) deactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermUsersManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	userID := r.PathValue("id")
+	if err := s.authProvider.DeactivateUser(r.Context(), userID); err != nil {
+		s.respondWithAccountError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, userID, auth.AuditAccountDeactivated, nil)
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deactivated"})
+}
+
+func (s *Server) reactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermUsersManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	userID := r.PathValue("id")
+	if err := s.authProvider.ReactivateUser(r.Context(), userID); err != nil {
+		s.respondWithAccountError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, userID, auth.AuditAccountReactivated, nil)
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "active"})
+}
+
+func (s *Server) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermUsersManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	userID := r.PathValue("id")
+	if err := s.authProvider.DeleteUser(r.Context(), userID); err != nil {
+		s.respondWithAccountError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, userID, auth.AuditAccountDeleted, nil)
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (s *Server) respondWithAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		s.respondWithError(w, http.StatusUnauthorized, "invalid credentials")
+	case errors.Is(err, auth.ErrUserNotFound):
+		s.respondWithError(w, http.StatusNotFound, "user not found")
+	default:
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/policy"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+)
+
+// maxAccessChecks bounds the size of one batch of authorization checks.
+const maxAccessChecks = 100
+
+// Authorization decisions...
+
+// checkAccessHandler answers authorization questions for other services. The
+// subject is either an access token, which is enough on its own, or a user
+// ID, which requires the caller to hold the authz:check permission.
+func (s *Server) checkAccessHandler(w http.ResponseWriter, r *http.Request) {
+	var req AccessCheckData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	checks, err := accessChecks(req)
+	if err != nil {
+		s.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var userID string
+	switch {
+	case req.Subject.Token != "" && req.Subject.UserID == "":
+		claims, err := s.tokensProvider.ValidateAccessToken(req.Subject.Token)
+		if err == nil {
+			err = s.tokensProvider.CheckSession(r.Context(), claims)
+		}
+		if err != nil {
+			s.respondWithJSON(w, http.StatusOK, AccessDecisions{Decisions: deniedDecisions(checks, "invalid subject token")})
+			return
+		}
+		userID = claims.UserID
+	case req.Subject.UserID != "" && req.Subject.Token == "":
+		if _, status, err := s.requirePermission(r, auth.PermAuthzCheck); err != nil {
+			s.respondWithError(w, status, err.Error())
+			return
+		}
+		userID = req.Subject.UserID
+	default:
+		s.respondWithError(w, http.StatusBadRequest, "subject needs either a token or a user_id")
+		return
+	}
+
+	decisions, err := s.authProvider.CheckAccess(r.Context(), userID, providerChecks(checks))
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := AccessDecisions{UserID: userID, Allowed: true, Decisions: make([]AccessDecision, 0, len(decisions))}
+	for _, decision := range decisions {
+		resp.Allowed = resp.Allowed && decision.Allowed
+		resp.Decisions = append(resp.Decisions, AccessDecision(decision))
+	}
+	s.respondWithJSON(w, http.StatusOK, resp)
+}
+
+// explainAccessHandler shows how decisions come about, for debugging
+// policies. With policies in the body, those are evaluated instead of the
+// loaded ones, so a policy file can be tried out before it is deployed.
+func (s *Server) explainAccessHandler(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := s.requirePermission(r, auth.PermAuthzCheck); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	var req ExplainAccessData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	checks, err := accessChecks(req.AccessCheckData)
+	if err != nil {
+		s.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := req.Subject.UserID
+	if req.Subject.Token != "" {
+		claims, err := s.tokensProvider.ValidateAccessToken(req.Subject.Token)
+		if err == nil {
+			err = s.tokensProvider.CheckSession(r.Context(), claims)
+		}
+		if err != nil {
+			s.respondWithError(w, http.StatusBadRequest, "invalid subject token")
+			return
+		}
+		userID = claims.UserID
+	}
+	if userID == "" {
+		s.respondWithError(w, http.StatusBadRequest, "subject needs either a token or a user_id")
+		return
+	}
+
+	var policies *policy.Set
+	if req.Policies != "" {
+		if policies, err = policy.Parse([]byte(req.Policies)); err != nil {
+			s.respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	explanations, err := s.authProvider.ExplainAccess(r.Context(), userID, providerChecks(checks), policies)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := AccessExplanations{UserID: userID, Allowed: true, DryRun: req.Policies != "",
+		Decisions: make([]AccessExplanation, 0, len(explanations))}
+	for _, explanation := range explanations {
+		resp.Allowed = resp.Allowed && explanation.Allowed
+		resp.Decisions = append(resp.Decisions, ProviderAccessExplanation2Server(explanation))
+	}
+	s.respondWithJSON(w, http.StatusOK, resp)
+}
+
+// accessChecks accepts a single action and resource or a batch of checks.
+func accessChecks(req AccessCheckData) ([]AccessCheck, error) {
+	checks := req.Checks
+	if len(checks) == 0 && req.Action != "" {
+		checks = []AccessCheck{{Action: req.Action, Resource: req.Resource}}
+	}
+	if len(checks) == 0 || len(checks) > maxAccessChecks {
+		return nil, fmt.Errorf("between 1 and %d checks are required", maxAccessChecks)
+	}
+	return checks, nil
+}
+
+func providerChecks(checks []AccessCheck) []auth.AccessCheck {
+	result := make([]auth.AccessCheck, 0, len(checks))
+	for _, check := range checks {
+		result = append(result, auth.AccessCheck(check))
+	}
+	return result
+}
+
+func deniedDecisions(checks []AccessCheck, reason string) []AccessDecision {
+	decisions := make([]AccessDecision, 0, len(checks))
+	for _, check := range checks {
+		decisions = append(decisions, AccessDecision{Action: check.Action, Resource: check.Resource, Reason: reason})
+	}
+	return decisions
+}
+
+// Teacher groups...
+
+func (s *Server) getTeacherGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := s.requirePermission(r, auth.PermRolesRead); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	userID := r.PathValue("id")
+	groups, err := s.authProvider.GetTeacherGroups(r.Context(), userID)
+	if err != nil {
+		s.respondWithTeacherGroupError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, TeacherGroups{UserID: userID, Groups: groups})
+}
+
+func (s *Server) assignTeacherGroupHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermUsersManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	userID, groupID := r.PathValue("id"), r.PathValue("group_id")
+	if err := s.authProvider.AssignTeacherGroup(r.Context(), userID, groupID); err != nil {
+		s.respondWithTeacherGroupError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, userID, auth.AuditGroupAssigned, map[string]any{"group_id": groupID})
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "assigned"})
+}
+
+func (s *Server) unassignTeacherGroupHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermUsersManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	userID, groupID := r.PathValue("id"), r.PathValue("group_id")
+	if err := s.authProvider.UnassignTeacherGroup(r.Context(), userID, groupID); err != nil {
+		s.respondWithTeacherGroupError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, userID, auth.AuditGroupUnassigned, map[string]any{"group_id": groupID})
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "unassigned"})
+}
+
+func (s *Server) respondWithTeacherGroupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidResource):
+		s.respondWithError(w, http.StatusBadRequest, "group_id must be a uuid")
+	case errors.Is(err, auth.ErrUserNotFound), errors.Is(err, auth.ErrResourceNotFound):
+		s.respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, auth.ErrNotTeacher):
+		s.respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+)
+
+func (s *Server) requestEmailLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.emailLogin.enabled {
+		s.respondWithError(w, http.StatusNotFound, "email login is disabled")
+		return
+	}
+
+	var req EmailLoginData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "invalid request format")
+		return
+	}
+
+	if req.Email == "" {
+		s.respondWithError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+	if req.Method == "" {
+		req.Method = auth.EmailLoginLink
+	}
+
+	if !s.emailLogin.perIP.Allow(clientIP(r)) ||
+		!s.emailLogin.perAddress.Allow(strings.ToLower(req.Email)) {
+		s.respondWithError(w, http.StatusTooManyRequests, "too many requests")
+		return
+	}
+
+	if err := s.authProvider.RequestEmailLogin(r.Context(), req.Email, req.Method); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidLoginMethod):
+			s.respondWithError(w, http.StatusBadRequest, "method must be link or code")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusAccepted, map[string]string{"status": "sent"})
+}
+
+func (s *Server) verifyEmailLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.emailLogin.enabled {
+		s.respondWithError(w, http.StatusNotFound, "email login is disabled")
+		return
+	}
+
+	var req VerifyEmailLoginData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "invalid request format")
+		return
+	}
+
+	if !s.emailLogin.perIP.Allow(clientIP(r)) {
+		s.respondWithError(w, http.StatusTooManyRequests, "too many requests")
+		return
+	}
+
+	var userID string
+	var err error
+	switch {
+	case req.Token != "":
+		userID, err = s.authProvider.VerifyEmailLink(r.Context(), req.Token, clientInfo(r))
+	case req.Email != "" && req.Code != "":
+		userID, err = s.authProvider.VerifyEmailCode(r.Context(), req.Email, req.Code, clientInfo(r))
+	default:
+		s.respondWithError(w, http.StatusBadRequest, "token or email and code are required")
+		return
+	}
+	if err != nil {
+		switch {
+		// Locked accounts get the same answer, see loginUserHandler.
+		case errors.Is(err, auth.ErrInvalidEmailLogin), errors.Is(err, auth.ErrAccountLocked):
+			s.respondWithError(w, http.StatusUnauthorized, "invalid or expired link or code")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	s.completeLogin(w, r, userID)
+}
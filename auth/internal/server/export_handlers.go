@@ -0,0 +1,145 @@
+package server
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+)
+
+const (
+	exportFormatJSON = "json"
+	exportFormatZIP  = "zip"
+)
+
+func (s *Server) exportMeHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	s.respondWithExport(w, r, claims.UserID, claims.UserID)
+}
+
+func (s *Server) exportUserHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermUsersExport)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	s.respondWithExport(w, r, claims.UserID, r.PathValue("id"))
+}
+
+func (s *Server) respondWithExport(w http.ResponseWriter, r *http.Request, actorID, userID string) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatJSON
+	}
+	if format != exportFormatJSON && format != exportFormatZIP {
+		s.respondWithError(w, http.StatusBadRequest, "format must be json or zip")
+		return
+	}
+
+	export, err := s.buildExport(r, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUserNotFound):
+			s.respondWithError(w, http.StatusNotFound, "user not found")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	s.audit(r, actorID, userID, auth.AuditDataExported, map[string]any{"format": format})
+
+	filename := fmt.Sprintf("user-%s-export.%s", userID, format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == exportFormatJSON {
+		s.respondWithJSON(w, http.StatusOK, export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+	if err := writeExportZIP(w, export); err != nil {
+		// Headers are sent already, all that is left is to log.
+		log.Default().Println("[ERR] write export archive:", err.Error())
+	}
+}
+
+func (s *Server) buildExport(r *http.Request, userID string) (DataExport, error) {
+	data, err := s.authProvider.ExportUserData(r.Context(), userID)
+	if err != nil {
+		return DataExport{}, err
+	}
+	export := ProviderDataExport2Server(data)
+	export.ExportedAt = time.Now().UTC().Format(time.RFC3339)
+
+	methods, err := s.mfaProvider.Methods(r.Context(), userID)
+	if err != nil {
+		return DataExport{}, fmt.Errorf("failed to get mfa methods: %w", err)
+	}
+	export.Sessions.MFAMethods = append([]string{}, methods...)
+
+	passkeys, err := s.mfaProvider.ListPasskeys(r.Context(), userID)
+	if err != nil {
+		return DataExport{}, fmt.Errorf("failed to get passkeys: %w", err)
+	}
+	export.Sessions.Passkeys = make([]Passkey, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		export.Sessions.Passkeys = append(export.Sessions.Passkeys, ProviderPasskey2Server(passkey))
+	}
+
+	return export, nil
+}
+
+// writeExportZIP puts every section of the export into its own JSON file.
+func writeExportZIP(w http.ResponseWriter, export DataExport) error {
+	profiles := map[string]any{}
+	if export.Student != nil {
+		profiles["student"] = export.Student
+	}
+	if export.Teacher != nil {
+		profiles["teacher"] = export.Teacher
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"user.json", export.User},
+		{"roles.json", export.Roles},
+		{"profiles.json", profiles},
+		{"sessions.json", export.Sessions},
+		{"login_history.json", export.LoginHistory},
+		{"username_history.json", export.UsernameHistory},
+		{"email_changes.json", export.EmailChanges},
+		{"activation_keys.json", export.ActivationKeys},
+		{"issued_keys.json", export.IssuedKeys},
+		{"role_requests.json", export.RoleRequests},
+		{"audit_events.json", export.AuditEvents},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -6,9 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
 	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
 )
@@ -28,45 +31,44 @@ func (s *Server) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := s.authProvider.RegisterUser(r.Context(), ServerRegisterReq2Provider(req))
+	var keyClaims jwt.MapClaims
+	if req.ActivationKey != "" {
+		var err error
+		if keyClaims, err = s.tokensProvider.ValidateRoleKey(req.ActivationKey); err != nil {
+			s.respondWithError(w, http.StatusBadRequest, "invalid activation key")
+			return
+		}
+	}
+
+	userID, err := s.authProvider.RegisterUser(r.Context(), ServerRegisterReq2Provider(req), keyClaims)
 	if err != nil {
 		switch {
+		case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrInvalidEmail):
+			s.respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, auth.ErrInvalidActivationKey):
+			s.respondWithError(w, http.StatusBadRequest, "invalid activation key")
+		case errors.Is(err, auth.ErrActivationKeyRedeemed):
+			s.respondWithError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, auth.ErrActivationKeyRequired), errors.Is(err, auth.ErrEmailDomainNotAllowed),
+			errors.Is(err, auth.ErrRegistrationClosed):
+			s.respondWithError(w, http.StatusForbidden, err.Error())
 		case errors.Is(err, auth.ErrEmailExists):
 			s.respondWithError(w, http.StatusConflict, "email exists")
 		case errors.Is(err, auth.ErrUsernameExists):
 			s.respondWithError(w, http.StatusConflict, "username exists")
+		case errors.Is(err, auth.ErrUsernameReserved):
+			s.respondWithError(w, http.StatusConflict, "username is reserved")
 		default:
 			s.respondWithError(w, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
 
-	accessToken, err := s.tokensProvider.GenerateAccessToken(userID)
-	if err != nil {
-		switch {
-		case errors.Is(err, tokens.ErrAccessGenerate):
-			s.respondWithError(w, http.StatusConflict, "access generate error: "+err.Error())
-		default:
-			s.respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
-		return
+	if keyClaims != nil {
+		s.audit(r, userID, userID, auth.AuditRoleActivated, map[string]any{"role": keyClaims["role"]})
 	}
 
-	refreshToken, err := s.tokensProvider.GenerateRefreshToken(userID)
-	if err != nil {
-		switch {
-		case errors.Is(err, tokens.ErrRefreshGenerate):
-			s.respondWithError(w, http.StatusConflict, "refresh generate error: "+err.Error())
-		default:
-			s.respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
-		return
-	}
-
-	s.respondWithJSON(w, http.StatusCreated, Tokens{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	})
+	s.respondWithTokens(w, r, http.StatusCreated, userID)
 }
 
 func (s *Server) loginUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -82,44 +84,20 @@ func (s *Server) loginUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := s.authProvider.LoginUser(r.Context(), req.Login, req.Password)
+	userID, err := s.authProvider.LoginUser(r.Context(), req.Login, req.Password, clientInfo(r))
 	if err != nil {
 		switch {
-		case errors.Is(err, auth.ErrIncorrectPassword):
-			s.respondWithError(w, http.StatusUnauthorized, "incorrect password")
-		case errors.Is(err, auth.ErrUserNotFound):
-			s.respondWithError(w, http.StatusNotFound, "user not found")
-		default:
-			s.respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
-		return
-	}
-	accessToken, err := s.tokensProvider.GenerateAccessToken(userID)
-	if err != nil {
-		switch {
-		case errors.Is(err, tokens.ErrAccessGenerate):
-			s.respondWithError(w, http.StatusConflict, "access generate error: "+err.Error())
+		// Locked accounts get the same answer, otherwise the lockout itself
+		// would tell which logins exist.
+		case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, auth.ErrAccountLocked):
+			s.respondWithError(w, http.StatusUnauthorized, "invalid credentials")
 		default:
 			s.respondWithError(w, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
 
-	refreshToken, err := s.tokensProvider.GenerateRefreshToken(userID)
-	if err != nil {
-		switch {
-		case errors.Is(err, tokens.ErrRefreshGenerate):
-			s.respondWithError(w, http.StatusConflict, "refresh generate error: "+err.Error())
-		default:
-			s.respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
-		return
-	}
-
-	s.respondWithJSON(w, http.StatusCreated, Tokens{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	})
+	s.completeLogin(w, r, userID)
 }
 
 func (s *Server) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
@@ -149,68 +127,46 @@ func (s *Server) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, err := s.tokensProvider.GenerateAccessToken(claims.UserID)
-	if err != nil {
-		switch {
-		case errors.Is(err, tokens.ErrAccessGenerate):
-			s.respondWithError(w, http.StatusConflict, "access generate error: "+err.Error())
-		default:
-			s.respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
-		return
-	}
-
-	refreshToken, err := s.tokensProvider.GenerateRefreshToken(claims.UserID)
-	if err != nil {
+	if err := s.tokensProvider.CheckSession(r.Context(), claims); err != nil {
 		switch {
-		case errors.Is(err, tokens.ErrRefreshGenerate):
-			s.respondWithError(w, http.StatusConflict, "refresh generate error: "+err.Error())
+		case errors.Is(err, tokens.ErrSessionRevoked):
+			s.respondWithError(w, http.StatusUnauthorized, "session revoked")
 		default:
 			s.respondWithError(w, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
 
-	s.respondWithJSON(w, http.StatusCreated, Tokens{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	})
+	s.respondWithTokens(w, r, http.StatusCreated, claims.UserID)
 }
 
 // Keys
 
 func (s *Server) generateKeyHandler(w http.ResponseWriter, r *http.Request) {
-	// TO DO Middleware or check for admin
-
-	var req struct {
-		Role           string `json:"role"`
-		GroupID        string `json:"group_id,omitempty"`
-		UniversityID   string `json:"university_id"`
-		EnrollmentYear int    `json:"enrollment_year,omitempty"`
-		Degree         string `json:"degree,omitempty"`
+	claims, status, err := s.requirePermission(r, auth.PermKeysGenerate)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
 	}
 
+	var req KeyData
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.respondWithError(w, http.StatusBadRequest, "invalid request")
 		return
 	}
 
-	switch req.Role {
-	case "student":
-		if req.GroupID == "" || req.UniversityID == "" || req.EnrollmentYear == 0 {
-			s.respondWithError(w, http.StatusBadRequest, "group_id, university_id and enrollment_year are required for student")
-			return
-		}
-	case "teacher":
-		if req.UniversityID == "" || req.Degree == "" {
-			s.respondWithError(w, http.StatusBadRequest, "university_id and degree are required for teacher")
-			return
-		}
-	default:
-		s.respondWithError(w, http.StatusBadRequest, "invalid role")
+	if err := auth.ValidateKeyRequest(auth.KeyRequest(req)); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	s.issueKey(w, r, claims.UserID, auth.KeyRequest(req), false)
+}
+
+// issueKey generates the activation key and records who issued it. The key
+// is only handed out once the issuance is stored, for delegated keys within
+// the issuer's quota.
+func (s *Server) issueKey(w http.ResponseWriter, r *http.Request, issuerID string, req auth.KeyRequest, delegated bool) {
 	key, err := s.tokensProvider.GenerateRoleKey(
 		req.Role,
 		req.GroupID,
@@ -223,11 +179,30 @@ func (s *Server) generateKeyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if claims, err := s.tokensProvider.ValidateRoleKey(key); err != nil {
+	keyClaims, err := s.tokensProvider.ValidateRoleKey(key)
+	if err != nil {
 		log.Default().Println("[ERR]: ", err.Error())
-	} else {
-		log.Default().Println("[GENERATED KEY INFO]: \n", claims)
+		s.respondWithError(w, http.StatusInternalServerError, "failed to generate key")
+		return
+	}
+
+	keyID, _ := keyClaims["jti"].(string)
+	if err := s.authProvider.RecordKeyIssuance(r.Context(), issuerID, keyID, req, delegated); err != nil {
+		if errors.Is(err, auth.ErrKeyQuotaExceeded) {
+			s.respondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
+	s.audit(r, issuerID, "", auth.AuditKeyIssued, map[string]any{
+		"key_id":          keyID,
+		"role":            req.Role,
+		"university_id":   req.UniversityID,
+		"group_id":        req.GroupID,
+		"enrollment_year": req.EnrollmentYear,
+		"degree":          req.Degree,
+	})
 
 	s.respondWithJSON(w, http.StatusOK, map[string]string{
 		"key": key,
@@ -256,29 +231,44 @@ func (s *Server) activateKeyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var activateErr error
-	switch keyClaims["role"] {
-	case "student":
-		activateErr = s.authProvider.ActivateStudent(r.Context(), userID, keyClaims)
-	case "teacher":
-		activateErr = s.authProvider.ActivateTeacher(r.Context(), userID, keyClaims)
-	default:
-		s.respondWithError(w, http.StatusBadRequest, "invalid role in activation key")
-		log.Default().Println(keyClaims["role"])
-		return
-	}
-
-	if activateErr != nil {
+	role, err := s.authProvider.ActivateKey(r.Context(), userID, keyClaims)
+	if err != nil {
 		switch {
-		case errors.Is(activateErr, auth.ErrUserNotFound):
-			s.respondWithError(w, http.StatusNotFound, "user not found")
+		case errors.Is(err, auth.ErrInvalidActivationKey):
+			s.respondWithError(w, http.StatusBadRequest, "invalid activation key")
+		case errors.Is(err, auth.ErrActivationKeyRedeemed), errors.Is(err, auth.ErrRoleAlreadyActivated):
+			s.respondWithError(w, http.StatusConflict, err.Error())
 		default:
-			s.respondWithError(w, http.StatusInternalServerError, activateErr.Error())
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
 
-	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "success"})
+	s.audit(r, userID, userID, auth.AuditRoleActivated, map[string]any{"role": role})
+
+	enrollmentRequired, err := s.mfaProvider.RequiresEnrollment(r.Context(), userID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !enrollmentRequired {
+		s.respondWithJSON(w, http.StatusOK, ActivateKeyResult{Status: "success"})
+		return
+	}
+
+	enrollmentToken, err := s.tokensProvider.GenerateEnrollmentToken(userID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, "enrollment token generate error: "+err.Error())
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ActivateKeyResult{
+		Status: "success",
+		MFAEnrollmentChallenge: &MFAEnrollmentChallenge{
+			MFAEnrollmentRequired: true,
+			EnrollmentToken:       enrollmentToken,
+		},
+	})
 }
 
 // User Info...
@@ -290,6 +280,12 @@ func (s *Server) getUserByIdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	claims, status, err := s.authorize(r, auth.PermUsersRead, auth.ResourceUser+":"+userID)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
 	user, err := s.authProvider.GetUserByID(context.Background(), userID)
 
 	if err != nil {
@@ -302,19 +298,28 @@ func (s *Server) getUserByIdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := ProviderUser2Server(user)
+	visible, err := s.visibilities(r, claims.UserID, auth.PermUsersRead, []string{userID})
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := ProviderUser2Server(visible[userID].user(user))
 	s.respondWithJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) getUserByEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := s.requirePermission(r, auth.PermUsersReadEmail); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
 	email := r.PathValue("email")
 	if email == "" {
 		s.respondWithError(w, http.StatusBadRequest, "email is required")
 		return
 	}
 
-	log.Default().Println(email)
-
 	user, err := s.authProvider.GetUserByEmail(context.Background(), email)
 
 	if err != nil {
@@ -338,6 +343,12 @@ func (s *Server) getStudentByIdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	claims, status, err := s.authorize(r, auth.PermStudentsRead, auth.ResourceUser+":"+studentID)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
 	student, err := s.authProvider.GetStudentByID(r.Context(), studentID)
 	if err != nil {
 		switch {
@@ -349,13 +360,26 @@ func (s *Server) getStudentByIdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.respondWithJSON(w, http.StatusOK, student)
+	visible, err := s.visibilities(r, claims.UserID, auth.PermStudentsRead, []string{studentID})
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, visible[studentID].student(student))
 }
 
+// getStudentsByGroupHandler lists a group to callers allowed to read its
+// roster. Students the caller may not read individually, such as classmates,
+// are reduced to names and group.
 func (s *Server) getStudentsByGroupHandler(w http.ResponseWriter, r *http.Request) {
 	groupIDs := r.PathValue("id")
 
-	log.Default().Println("[GROUP ID] ", groupIDs)
+	claims, status, err := s.authorize(r, auth.PermStudentsReadGroup, auth.ResourceGroup+":"+groupIDs)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
 
 	students, err := s.authProvider.GetStudents(r.Context(), groupIDs)
 	if err != nil {
@@ -363,6 +387,19 @@ func (s *Server) getStudentsByGroupHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	userIDs := make([]string, 0, len(students))
+	for _, student := range students {
+		userIDs = append(userIDs, student.ID)
+	}
+	visible, err := s.visibilities(r, claims.UserID, auth.PermStudentsRead, userIDs)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for i, student := range students {
+		students[i] = visible[student.ID].student(student)
+	}
 	s.respondWithJSON(w, http.StatusOK, students)
 }
 
@@ -373,6 +410,12 @@ func (s *Server) getTeacherByIdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	claims, status, err := s.authorize(r, auth.PermTeachersRead, auth.ResourceUser+":"+teacherID)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
 	teacher, err := s.authProvider.GetTeacherByID(r.Context(), teacherID)
 	if err != nil {
 		switch {
@@ -384,13 +427,23 @@ func (s *Server) getTeacherByIdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.respondWithJSON(w, http.StatusOK, teacher)
+	visible, err := s.visibilities(r, claims.UserID, auth.PermTeachersRead, []string{teacherID})
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, visible[teacherID].teacher(teacher))
 }
 
 func (s *Server) getTeachersByUniHandler(w http.ResponseWriter, r *http.Request) {
 	uniIDs := r.PathValue("id")
 
-	log.Default().Println("[UNI ID] ", uniIDs)
+	claims, status, err := s.authorize(r, auth.PermTeachersReadUniversity, auth.ResourceUniversity+":"+uniIDs)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
 
 	teachers, err := s.authProvider.GetTeachers(r.Context(), uniIDs)
 	if err != nil {
@@ -398,23 +451,45 @@ func (s *Server) getTeachersByUniHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	userIDs := make([]string, 0, len(teachers))
+	for _, teacher := range teachers {
+		userIDs = append(userIDs, teacher.ID)
+	}
+	visible, err := s.visibilities(r, claims.UserID, auth.PermTeachersRead, userIDs)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for i, teacher := range teachers {
+		teachers[i] = visible[teacher.ID].teacher(teacher)
+	}
 	s.respondWithJSON(w, http.StatusOK, teachers)
 }
 
 func (s *Server) getUserRoleByIdHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.PathValue("id")
 
-	roles, err := s.authProvider.GetUserRoles(r.Context(), userID)
+	if _, status, err := s.authorize(r, auth.PermRolesRead, auth.ResourceUser+":"+userID); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	assignments, err := s.authProvider.GetUserRoles(r.Context(), userID)
 	if err != nil {
 		log.Printf("GetUserRoles error: %v", err)
 		s.respondWithError(w, http.StatusInternalServerError, "failed to get user roles")
 		return
 	}
 
-	s.respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"user_id": userID,
-		"roles":   roles,
-	})
+	resp := UserRoles{UserID: userID, Roles: []string{}, Assignments: make([]RoleAssignment, 0, len(assignments))}
+	for _, assignment := range assignments {
+		if !slices.Contains(resp.Roles, assignment.Role) {
+			resp.Roles = append(resp.Roles, assignment.Role)
+		}
+		resp.Assignments = append(resp.Assignments, ProviderRoleAssignment2Server(assignment))
+	}
+	s.respondWithJSON(w, http.StatusOK, resp)
 }
 
 // Server ...
@@ -424,30 +499,241 @@ func (s *Server) pingHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) getClaimsFromRequest(r *http.Request) (*tokens.Claims, error) {
+	tokenString, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.tokensProvider.ValidateAccessToken(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if err := s.tokensProvider.CheckSession(r.Context(), claims); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	// Impersonation is for looking at what the user sees, never for acting
+	// on their behalf.
+	if claims.Act != nil && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return nil, tokens.ErrImpersonation
+	}
+
+	return claims, nil
+}
+
+// getEnrollmentClaimsFromRequest accepts enrollment-only tokens in addition
+// to access tokens. It is meant for second factor enrollment endpoints only.
+// Both kinds die with the user's sessions, otherwise a revoked token could
+// enroll a new factor and log in with it.
+func (s *Server) getEnrollmentClaimsFromRequest(r *http.Request) (*tokens.Claims, error) {
+	tokenString, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.tokensProvider.ValidateAccessToken(tokenString)
+	if err != nil {
+		if claims, err = s.tokensProvider.ValidateEnrollmentToken(tokenString); err != nil {
+			return nil, fmt.Errorf("invalid token: %w", err)
+		}
+	}
+
+	// Enrolling a factor for the user is acting on their behalf.
+	if claims.Act != nil {
+		return nil, tokens.ErrImpersonation
+	}
+
+	if err := s.tokensProvider.CheckSession(r.Context(), claims); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	return claims, nil
+}
+
+func bearerToken(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return nil, errors.New("authorization header is required")
+		return "", errors.New("authorization header is required")
 	}
 
 	const bearerPrefix = "Bearer "
 	if !strings.HasPrefix(authHeader, bearerPrefix) {
-		return nil, errors.New("authorization header format must be 'Bearer {token}'")
+		return "", errors.New("authorization header format must be 'Bearer {token}'")
 	}
 
-	tokenString := strings.TrimPrefix(authHeader, bearerPrefix)
+	return strings.TrimPrefix(authHeader, bearerPrefix), nil
+}
 
-	claims, err := s.tokensProvider.ValidateRefreshToken(tokenString)
+// completeLogin finishes a successful first factor: users with MFA get a
+// challenge, everybody else gets tokens.
+func (s *Server) completeLogin(w http.ResponseWriter, r *http.Request, userID string) {
+	if !s.checkActive(w, r, userID) {
+		return
+	}
+
+	mfaEnabled, err := s.mfaProvider.IsEnabled(r.Context(), userID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid token: %w", err)
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if mfaEnabled {
+		s.respondWithMFAChallenge(w, r, userID)
+		return
 	}
 
-	return claims, nil
+	s.respondWithTokens(w, r, http.StatusCreated, userID)
+}
+
+// checkActive responds with an error and returns false when the account is
+// deactivated or deleted.
+func (s *Server) checkActive(w http.ResponseWriter, r *http.Request, userID string) bool {
+	if err := s.authProvider.CheckActive(r.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrAccountDisabled):
+			s.respondWithError(w, http.StatusForbidden, "account is deactivated")
+		case errors.Is(err, auth.ErrUserNotFound):
+			s.respondWithError(w, http.StatusNotFound, "user not found")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+func (s *Server) audit(r *http.Request, actorID, subjectID, action string, details map[string]any) {
+	s.authProvider.RecordAuditEvent(r.Context(), auth.AuditEvent{
+		ActorID:   actorID,
+		SubjectID: subjectID,
+		Action:    action,
+		Details:   details,
+		IP:        clientIP(r),
+	})
+}
+
+func clientInfo(r *http.Request) auth.ClientInfo {
+	return auth.ClientInfo{
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requirePermission authenticates the request and checks that one of the
+// caller's global role assignments grants the permission. Callers holding a
+// role covered by the MFA policy get nothing until they have enrolled a second
+// factor. The returned status code is meant for the error response.
+func (s *Server) requirePermission(r *http.Request, permission string) (*tokens.Claims, int, error) {
+	return s.requireScopedPermission(r, permission, auth.Resource{})
+}
+
+// requireScopedPermission is requirePermission for a resource that belongs to
+// a university or group, so that assignments scoped to those count as well.
+func (s *Server) requireScopedPermission(r *http.Request, permission string, resource auth.Resource) (*tokens.Claims, int, error) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		return nil, http.StatusUnauthorized, errors.New("invalid token")
+	}
+
+	granted, err := s.authProvider.HasPermission(r.Context(), claims.UserID, permission, resource)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("failed to check permissions")
+	}
+	if !granted {
+		return nil, http.StatusForbidden, errors.New("forbidden")
+	}
+
+	if status, err := s.checkEnrollment(r, claims.UserID); err != nil {
+		return nil, status, err
+	}
+
+	return claims, http.StatusOK, nil
+}
+
+// checkEnrollment refuses callers that hold a role covered by the MFA policy
+// but have not enrolled a second factor yet.
+func (s *Server) checkEnrollment(r *http.Request, userID string) (int, error) {
+	enrollmentRequired, err := s.mfaProvider.RequiresEnrollment(r.Context(), userID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("failed to check mfa")
+	}
+	if enrollmentRequired {
+		return http.StatusForbidden, errors.New("mfa enrollment required")
+	}
+	return http.StatusOK, nil
+}
+
+// respondWithTokens issues a token pair, unless the MFA policy requires the
+// user to enroll a second factor first. In that case only an enrollment
+// token is returned.
+func (s *Server) respondWithTokens(w http.ResponseWriter, r *http.Request, code int, userID string) {
+	if !s.checkActive(w, r, userID) {
+		return
+	}
+
+	enrollmentRequired, err := s.mfaProvider.RequiresEnrollment(r.Context(), userID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if enrollmentRequired {
+		s.respondWithEnrollmentChallenge(w, userID)
+		return
+	}
+
+	pair, status, err := s.generateTokens(r.Context(), userID)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	s.respondWithJSON(w, code, pair)
+}
+
+func (s *Server) generateTokens(ctx context.Context, userID string) (Tokens, int, error) {
+	roles, err := s.authProvider.GetUserRoleNames(ctx, userID)
+	if err != nil {
+		return Tokens{}, http.StatusInternalServerError, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	accessToken, err := s.tokensProvider.GenerateAccessToken(userID, roles)
+	if err != nil {
+		switch {
+		case errors.Is(err, tokens.ErrAccessGenerate):
+			return Tokens{}, http.StatusConflict, fmt.Errorf("access generate error: %w", err)
+		default:
+			return Tokens{}, http.StatusInternalServerError, err
+		}
+	}
+
+	refreshToken, err := s.tokensProvider.GenerateRefreshToken(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, tokens.ErrRefreshGenerate):
+			return Tokens{}, http.StatusConflict, fmt.Errorf("refresh generate error: %w", err)
+		default:
+			return Tokens{}, http.StatusInternalServerError, err
+		}
+	}
+
+	return Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, http.StatusOK, nil
 }
 
 // Responces:
 func setCommonHeaders(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 }
 
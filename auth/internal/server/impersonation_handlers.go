@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
+)
+
+// Impersonation...
+
+// impersonateHandler issues a short-lived access token of the user with an
+// act claim naming the admin, so that support can see what the user sees in
+// other services. There is no refresh token, the token is read-only here and
+// dies with the admin's session. Admins can not be impersonated.
+func (s *Server) impersonateHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermUsersImpersonate)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	var req ImpersonateData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		s.respondWithError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+
+	userID := r.PathValue("id")
+	if userID == claims.UserID {
+		s.respondWithError(w, http.StatusBadRequest, "can not impersonate yourself")
+		return
+	}
+	if !s.checkActive(w, r, userID) {
+		return
+	}
+
+	if privileged, err := s.authProvider.HasPermission(r.Context(), userID, auth.PermUsersImpersonate, auth.Resource{}); err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, "failed to check permissions")
+		return
+	} else if privileged {
+		s.respondWithError(w, http.StatusForbidden, "can not impersonate an admin")
+		return
+	}
+
+	roles, err := s.authProvider.GetUserRoleNames(r.Context(), userID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, "failed to get user roles")
+		return
+	}
+
+	token, err := s.tokensProvider.GenerateImpersonationToken(userID, roles, claims.UserID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// The token only leaves the server once the event is stored, an
+	// impersonation must never go unaudited.
+	err = s.authProvider.CreateAuditEvent(r.Context(), auth.AuditEvent{
+		ActorID:   claims.UserID,
+		SubjectID: userID,
+		Action:    auth.AuditUserImpersonated,
+		Details: map[string]any{
+			"reason":     req.Reason,
+			"token_id":   token.ID,
+			"expires_at": token.ExpiresAt,
+		},
+		IP: clientIP(r),
+	})
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, "failed to record the impersonation")
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusCreated, Impersonation{
+		AccessToken:  token.AccessToken,
+		ExpiresAt:    token.ExpiresAt.Format(time.RFC3339),
+		UserID:       userID,
+		Impersonator: claims.UserID,
+	})
+}
+
+// rejectImpersonation answers every request but GET and HEAD made with an
+// impersonation token with 403, before any handler runs. Handlers check the
+// act claim as well, this keeps a route that forgets it from being usable
+// and gives all of them the same status.
+func (s *Server) rejectImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if token, err := bearerToken(r); err == nil {
+				if claims, err := s.tokensProvider.ValidateAccessToken(token); err == nil && claims.Act != nil {
+					s.respondWithError(w, http.StatusForbidden, tokens.ErrImpersonation.Error())
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/config"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/mfa"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+const (
+	testUserID  = "6f1c2a9e-3b7d-4c55-9a10-2f8e4b6d7c01"
+	testAdminID = "0b8e5d3a-71c4-4f2e-8d6a-93e1c7b2f405"
+)
+
+// TestImpersonationTokensAreReadOnly sends an impersonation token to every
+// route registered in setRouter that is not a GET. The providers are empty,
+// so any request that got past the middleware would fail differently.
+func TestImpersonationTokensAreReadOnly(t *testing.T) {
+	s := newTestServer(t)
+	token := impersonationToken(t, s)
+
+	routes := registeredRoutes(t)
+	if len(routes) == 0 {
+		t.Fatal("no routes found in server.go")
+	}
+
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		if method == http.MethodGet {
+			continue
+		}
+		t.Run(route, func(t *testing.T) {
+			req := httptest.NewRequest(method, pathWithIDs(path), strings.NewReader("{}"))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+
+			s.server.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("status %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRejectImpersonationLetsOtherRequestsThrough(t *testing.T) {
+	s := newTestServer(t)
+	impersonation := impersonationToken(t, s)
+	access, err := s.tokensProvider.GenerateAccessToken(testUserID, nil)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	handler := s.rejectImpersonation(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+	}{
+		{"GET with an impersonation token", http.MethodGet, impersonation},
+		{"HEAD with an impersonation token", http.MethodHead, impersonation},
+		{"POST with an access token", http.MethodPost, access},
+		{"POST without a token", http.MethodPost, ""},
+		{"POST with a malformed token", http.MethodPost, "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/users/me", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("status %d, want %d", rec.Code, http.StatusNoContent)
+			}
+		})
+	}
+}
+
+func TestEnrollmentClaimsRejectImpersonation(t *testing.T) {
+	s := newTestServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/users/me/mfa/totp/enroll", nil)
+	req.Header.Set("Authorization", "Bearer "+impersonationToken(t, s))
+
+	if _, err := s.getEnrollmentClaimsFromRequest(req); !errors.Is(err, tokens.ErrImpersonation) {
+		t.Fatalf("getEnrollmentClaimsFromRequest: got %v, want %v", err, tokens.ErrImpersonation)
+	}
+}
+
+// impersonationRepository lets testAdminID impersonate any other active
+// user. Methods the handler does not need panic through the nil interface.
+type impersonationRepository struct {
+	auth.Repository
+	auditErr error
+	audited  []models.AuditEvent
+}
+
+func (r *impersonationRepository) IsUserActive(ctx context.Context, userID string) (bool, error) {
+	return true, nil
+}
+
+func (r *impersonationRepository) HasPermission(ctx context.Context, userID, permission, universityID, groupID string) (bool, error) {
+	return userID == testAdminID, nil
+}
+
+func (r *impersonationRepository) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	return []string{"student"}, nil
+}
+
+func (r *impersonationRepository) CreateAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	if r.auditErr != nil {
+		return r.auditErr
+	}
+	r.audited = append(r.audited, event)
+	return nil
+}
+
+type activeSessions struct{}
+
+func (activeSessions) GetSessionState(ctx context.Context, userID string) (models.SessionState, error) {
+	return models.SessionState{Active: true}, nil
+}
+
+func TestImpersonateRequiresTheAuditEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		auditErr  error
+		wantCode  int
+		wantToken bool
+	}{
+		{"audit event stored", nil, http.StatusCreated, true},
+		{"audit event lost", errors.New("connection refused"), http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &impersonationRepository{auditErr: tt.auditErr}
+			s := newTestServer(t)
+			s.authProvider = auth.New(repo, nil, nil, nil, config.Config{})
+			s.tokensProvider = tokens.New(activeSessions{})
+
+			admin, err := s.tokensProvider.GenerateAccessToken(testAdminID, nil)
+			if err != nil {
+				t.Fatalf("GenerateAccessToken: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/admin/users/"+testUserID+"/impersonate",
+				strings.NewReader(`{"reason":"ticket 42"}`))
+			req.SetPathValue("id", testUserID)
+			req.Header.Set("Authorization", "Bearer "+admin)
+			rec := httptest.NewRecorder()
+
+			s.impersonateHandler(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if got := strings.Contains(rec.Body.String(), "access_token"); got != tt.wantToken {
+				t.Fatalf("token in response: got %v, want %v: %s", got, tt.wantToken, rec.Body.String())
+			}
+			if tt.wantToken && len(repo.audited) != 1 {
+				t.Fatalf("audit events: got %d, want 1", len(repo.audited))
+			}
+		})
+	}
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	if err := tokens.InitJWT("test-access-key", "test-refresh-key"); err != nil {
+		t.Fatalf("InitJWT: %v", err)
+	}
+	return New(config.Config{}, auth.AuthProvider{}, tokens.TokensProvider{}, mfa.MFAProvider{})
+}
+
+func impersonationToken(t *testing.T, s *Server) string {
+	t.Helper()
+	token, err := s.tokensProvider.GenerateImpersonationToken(testUserID, []string{"student"}, testAdminID)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationToken: %v", err)
+	}
+	return token.AccessToken
+}
+
+// registeredRoutes reads the patterns passed to mux.HandleFunc in setRouter,
+// so that routes added later are covered without touching this test.
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "server.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse server.go: %v", err)
+	}
+
+	var routes []string
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || selector.Sel.Name != "HandleFunc" {
+			return true
+		}
+		literal, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || literal.Kind != token.STRING {
+			return true
+		}
+		pattern, err := strconv.Unquote(literal.Value)
+		if err != nil {
+			t.Fatalf("route pattern %s: %v", literal.Value, err)
+		}
+		routes = append(routes, pattern)
+		return true
+	})
+	return routes
+}
+
+// pathWithIDs fills the wildcards of a route pattern with a UUID.
+func pathWithIDs(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = testUserID
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+)
+
+// Delegated keys...
+
+// generateGroupKeyHandler lets teachers issue student keys for the groups
+// they teach. The key is validated like the ones of /admin/generate-key and
+// additionally has to stay within the teacher's groups, university and
+// quota.
+func (s *Server) generateGroupKeyHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+	if status, err := s.checkEnrollment(r, claims.UserID); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	req := KeyData{Role: "student"}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	keyRequest := auth.KeyRequest(req)
+	if err := s.authProvider.CheckDelegatedKey(r.Context(), claims.UserID, &keyRequest); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidKeyRequest):
+			s.respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, auth.ErrKeyNotDelegable), errors.Is(err, auth.ErrNotTeacher),
+			errors.Is(err, auth.ErrKeyOutOfScope):
+			s.respondWithError(w, http.StatusForbidden, err.Error())
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	s.issueKey(w, r, claims.UserID, keyRequest, true)
+}
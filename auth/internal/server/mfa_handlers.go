@@ -0,0 +1,255 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/mfa"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
+)
+
+// MFA login step...
+
+func (s *Server) loginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var req LoginMFAData
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "invalid request format")
+		return
+	}
+
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		s.respondWithError(w, http.StatusBadRequest, "mfa_token and code or recovery_code are required")
+		return
+	}
+
+	claims, err := s.tokensProvider.ValidateMFAToken(req.MFAToken)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid mfa token")
+		return
+	}
+
+	if err := s.authProvider.CheckMFALockout(r.Context(), claims.UserID); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrAccountLocked):
+			s.respondWithError(w, http.StatusTooManyRequests, "too many failed attempts, try again later")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	method := auth.LoginMethodTOTP
+	if req.Code != "" {
+		err = s.mfaProvider.VerifyTOTP(r.Context(), claims.UserID, req.Code)
+	} else {
+		method = auth.LoginMethodRecoveryCode
+		err = s.mfaProvider.UseRecoveryCode(r.Context(), claims.UserID, req.RecoveryCode)
+	}
+	if errors.Is(err, mfa.ErrInvalidCode) {
+		s.authProvider.RecordMFAAttempt(r.Context(), claims.UserID, method, false, clientInfo(r))
+	}
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+	s.authProvider.RecordMFAAttempt(r.Context(), claims.UserID, method, true, clientInfo(r))
+
+	s.respondWithTokens(w, r, http.StatusCreated, claims.UserID)
+}
+
+// TOTP management...
+
+func (s *Server) enrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getEnrollmentClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	user, err := s.authProvider.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUserNotFound):
+			s.respondWithError(w, http.StatusNotFound, "user not found")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	enrollment, err := s.mfaProvider.EnrollTOTP(r.Context(), claims.UserID, user.Email)
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ProviderTOTPEnrollment2Server(enrollment))
+}
+
+func (s *Server) verifyTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getEnrollmentClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var req TOTPCodeData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		s.respondWithError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	// Enrollment tokens are exchanged for a token pair below, which needs an
+	// active account just like any other login.
+	if claims.TokenType == tokens.TokenTypeEnrollment && !s.checkActive(w, r, claims.UserID) {
+		return
+	}
+
+	recoveryCodes, err := s.mfaProvider.ConfirmTOTP(r.Context(), claims.UserID, req.Code)
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	resp := MFAEnrollmentResult{RecoveryCodes: recoveryCodes}
+	if claims.TokenType == tokens.TokenTypeEnrollment {
+		pair, status, err := s.generateTokens(r.Context(), claims.UserID)
+		if err != nil {
+			s.respondWithError(w, status, err.Error())
+			return
+		}
+		resp.Tokens = &pair
+	}
+
+	s.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var req TOTPCodeData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		s.respondWithError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	if err := s.mfaProvider.DisableTOTP(r.Context(), claims.UserID, req.Code); err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// Recovery codes...
+
+func (s *Server) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var req TOTPCodeData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		s.respondWithError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	recoveryCodes, err := s.mfaProvider.RegenerateRecoveryCodes(r.Context(), claims.UserID, req.Code)
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, RecoveryCodes{RecoveryCodes: recoveryCodes})
+}
+
+func (s *Server) countRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := s.requirePermission(r, auth.PermUsersAudit); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		s.respondWithError(w, http.StatusBadRequest, "user ID is required")
+		return
+	}
+
+	remaining, err := s.mfaProvider.CountRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, RecoveryCodesCount{
+		UserID:    userID,
+		Remaining: remaining,
+	})
+}
+
+func (s *Server) respondWithMFAChallenge(w http.ResponseWriter, r *http.Request, userID string) {
+	methods, err := s.mfaProvider.Methods(r.Context(), userID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	mfaToken, err := s.tokensProvider.GenerateMFAToken(userID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, "mfa token generate error: "+err.Error())
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, MFAChallenge{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		Methods:     methods,
+	})
+}
+
+func (s *Server) respondWithEnrollmentChallenge(w http.ResponseWriter, userID string) {
+	enrollmentToken, err := s.tokensProvider.GenerateEnrollmentToken(userID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, "enrollment token generate error: "+err.Error())
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, MFAEnrollmentChallenge{
+		MFAEnrollmentRequired: true,
+		EnrollmentToken:       enrollmentToken,
+	})
+}
+
+func (s *Server) respondWithMFAError(w http.ResponseWriter, err error) {
+	if status, ok := passkeyErrorStatus(err); ok {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	switch {
+	case errors.Is(err, mfa.ErrInvalidCode):
+		s.respondWithError(w, http.StatusUnauthorized, "invalid code")
+	case errors.Is(err, mfa.ErrTOTPNotEnrolled):
+		s.respondWithError(w, http.StatusNotFound, "totp is not enrolled")
+	case errors.Is(err, mfa.ErrTOTPAlreadyEnabled):
+		s.respondWithError(w, http.StatusConflict, "totp already enabled")
+	case errors.Is(err, mfa.ErrMFARequired):
+		s.respondWithError(w, http.StatusForbidden, "mfa is required for your role")
+	default:
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func qrCodeDataURI(png []byte) string {
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+}
@@ -1,7 +1,12 @@
 package server
 
 import (
+	"encoding/json"
+	"strings"
+	"time"
+
 	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/mfa"
 	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
 )
 
@@ -12,6 +17,8 @@ type RegisterUserData struct {
 	Password  string `json:"password"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	// ActivationKey is required in invite registration mode.
+	ActivationKey string `json:"activation_key,omitempty"`
 }
 
 func ServerRegisterReq2Provider(req RegisterUserData) auth.RegisterUserData {
@@ -50,25 +57,39 @@ type LoginUserData struct {
 	Password string `json:"password"`
 }
 
+// Email login info...
+type EmailLoginData struct {
+	Email  string `json:"email"`
+	Method string `json:"method"`
+}
+
+type VerifyEmailLoginData struct {
+	Token string `json:"token"`
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
 // User
 
 type User struct {
-	ID        string `json:"id"`
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	CreatedAt string `json:"created_at"`
+	ID           string `json:"id"`
+	Username     string `json:"username"`
+	Email        string `json:"email,omitempty"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	CreatedAt    string `json:"created_at,omitempty"`
+	UniversityID string `json:"university_id,omitempty"`
 }
 
 func ProviderUser2Server(user auth.User) User {
 	return User{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		CreatedAt: user.CreatedAt,
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		CreatedAt:    user.CreatedAt,
+		UniversityID: user.UniversityID,
 	}
 }
 
@@ -109,3 +130,569 @@ func ProviderTeacher2Server(teacher auth.Teacher) Teacher {
 		User:         ProviderUser2Server(teacher.User),
 	}
 }
+
+// Profile
+
+type Profile struct {
+	User    User     `json:"user"`
+	Roles   []string `json:"roles"`
+	Student *Student `json:"student,omitempty"`
+	Teacher *Teacher `json:"teacher,omitempty"`
+}
+
+func ProviderProfile2Server(profile auth.Profile) Profile {
+	resp := Profile{
+		User:  ProviderUser2Server(profile.User),
+		Roles: profile.Roles,
+	}
+	if resp.Roles == nil {
+		resp.Roles = []string{}
+	}
+	if profile.Student != nil {
+		student := ProviderStudent2Server(*profile.Student)
+		resp.Student = &student
+	}
+	if profile.Teacher != nil {
+		teacher := ProviderTeacher2Server(*profile.Teacher)
+		resp.Teacher = &teacher
+	}
+	return resp
+}
+
+type UpdateProfileData struct {
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Degree    *string `json:"degree"`
+}
+
+func ServerProfileUpdate2Provider(req UpdateProfileData) auth.ProfileUpdate {
+	return auth.ProfileUpdate{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Degree:    req.Degree,
+	}
+}
+
+type UsernameChangeData struct {
+	Username string `json:"username"`
+}
+
+type UsernameHistory struct {
+	Username      string `json:"username"`
+	ChangedAt     string `json:"changed_at"`
+	ReservedUntil string `json:"reserved_until"`
+}
+
+func ProviderUsernameHistory2Server(entry auth.UsernameHistory) UsernameHistory {
+	return UsernameHistory{
+		Username:      entry.Username,
+		ChangedAt:     entry.ChangedAt,
+		ReservedUntil: entry.ReservedUntil,
+	}
+}
+
+type IdentityConflict struct {
+	ID         string `json:"id"`
+	UserID     string `json:"user_id"`
+	Field      string `json:"field"`
+	Value      string `json:"value"`
+	KeptUserID string `json:"kept_user_id"`
+	DetectedAt string `json:"detected_at"`
+}
+
+type ConflictResolutionData struct {
+	Value string `json:"value"`
+}
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CreateRoleData struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type UserPermissions struct {
+	UserID            string             `json:"user_id"`
+	Permissions       []string           `json:"permissions"`
+	ScopedPermissions []ScopedPermission `json:"scoped_permissions"`
+}
+
+type ScopedPermission struct {
+	Permission string `json:"permission"`
+	ScopeType  string `json:"scope_type"`
+	ScopeID    string `json:"scope_id"`
+}
+
+func ProviderEffectivePermissions2Server(userID string, permissions auth.EffectivePermissions) UserPermissions {
+	scoped := make([]ScopedPermission, 0, len(permissions.Scoped))
+	for _, permission := range permissions.Scoped {
+		scoped = append(scoped, ScopedPermission{
+			Permission: permission.Permission,
+			ScopeType:  permission.Scope.Type,
+			ScopeID:    permission.Scope.ID,
+		})
+	}
+
+	return UserPermissions{
+		UserID:            userID,
+		Permissions:       permissions.Global,
+		ScopedPermissions: scoped,
+	}
+}
+
+type RoleAssignment struct {
+	Role      string `json:"role"`
+	ScopeType string `json:"scope_type"`
+	ScopeID   string `json:"scope_id"`
+	GrantedAt string `json:"granted_at,omitempty"`
+	GrantedBy string `json:"granted_by,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+func ProviderRoleAssignment2Server(assignment auth.RoleAssignment) RoleAssignment {
+	return RoleAssignment{
+		Role:      assignment.Role,
+		ScopeType: assignment.Scope.Type,
+		ScopeID:   assignment.Scope.ID,
+		GrantedAt: assignment.GrantedAt,
+		GrantedBy: assignment.GrantedBy,
+		ExpiresAt: assignment.ExpiresAt,
+	}
+}
+
+type UserRoles struct {
+	UserID      string           `json:"user_id"`
+	Roles       []string         `json:"roles"`
+	Assignments []RoleAssignment `json:"assignments"`
+}
+
+type GrantRoleData struct {
+	Role      string     `json:"role"`
+	ScopeType string     `json:"scope_type"`
+	ScopeID   string     `json:"scope_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type RevokeRoleData struct {
+	ScopeType string `json:"scope_type"`
+	ScopeID   string `json:"scope_id"`
+	Reason    string `json:"reason"`
+}
+
+type RevokedRoles struct {
+	UserID  string           `json:"user_id"`
+	Role    string           `json:"role"`
+	Reason  string           `json:"reason"`
+	Revoked []RoleAssignment `json:"revoked"`
+}
+
+type KeyData struct {
+	Role           string `json:"role"`
+	GroupID        string `json:"group_id,omitempty"`
+	UniversityID   string `json:"university_id"`
+	EnrollmentYear int    `json:"enrollment_year,omitempty"`
+	Degree         string `json:"degree,omitempty"`
+}
+
+type RoleRequestData struct {
+	Role           string `json:"role"`
+	UniversityID   string `json:"university_id"`
+	GroupID        string `json:"group_id"`
+	EnrollmentYear int    `json:"enrollment_year"`
+	Degree         string `json:"degree"`
+	Comment        string `json:"comment"`
+}
+
+type ReviewRoleRequestData struct {
+	Comment string `json:"comment"`
+}
+
+type RoleRequest struct {
+	ID             string `json:"id"`
+	UserID         string `json:"user_id"`
+	Role           string `json:"role"`
+	UniversityID   string `json:"university_id"`
+	GroupID        string `json:"group_id,omitempty"`
+	EnrollmentYear int    `json:"enrollment_year,omitempty"`
+	Degree         string `json:"degree,omitempty"`
+	Comment        string `json:"comment"`
+	Status         string `json:"status"`
+	ReviewedBy     string `json:"reviewed_by,omitempty"`
+	ReviewComment  string `json:"review_comment,omitempty"`
+	ReviewedAt     string `json:"reviewed_at,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+func ProviderRoleRequests2Server(requests []auth.RoleRequest) []RoleRequest {
+	resp := make([]RoleRequest, 0, len(requests))
+	for _, request := range requests {
+		resp = append(resp, RoleRequest(request))
+	}
+	return resp
+}
+
+type ImpersonateData struct {
+	Reason string `json:"reason"`
+}
+
+type Impersonation struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresAt    string `json:"expires_at"`
+	UserID       string `json:"user_id"`
+	Impersonator string `json:"impersonator"`
+}
+
+type AccessCheckData struct {
+	Subject  AccessSubject `json:"subject"`
+	Action   string        `json:"action"`
+	Resource string        `json:"resource"`
+	Checks   []AccessCheck `json:"checks"`
+}
+
+// AccessSubject identifies the user a decision is about, by exactly one of
+// an access token or a user ID.
+type AccessSubject struct {
+	Token  string `json:"token"`
+	UserID string `json:"user_id"`
+}
+
+type AccessCheck struct {
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+}
+
+type AccessDecision struct {
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+	Allowed  bool   `json:"allowed"`
+	Reason   string `json:"reason"`
+}
+
+type AccessDecisions struct {
+	UserID    string           `json:"user_id,omitempty"`
+	Allowed   bool             `json:"allowed"`
+	Decisions []AccessDecision `json:"decisions"`
+}
+
+type ExplainAccessData struct {
+	AccessCheckData
+	// Policies is a policy file to evaluate instead of the loaded one.
+	Policies string `json:"policies"`
+}
+
+type AccessExplanations struct {
+	UserID    string              `json:"user_id"`
+	Allowed   bool                `json:"allowed"`
+	DryRun    bool                `json:"dry_run"`
+	Decisions []AccessExplanation `json:"decisions"`
+}
+
+type AccessExplanation struct {
+	AccessDecision
+	Roles              RoleDecision   `json:"roles"`
+	SubjectAttributes  map[string]any `json:"subject_attributes"`
+	ResourceAttributes map[string]any `json:"resource_attributes"`
+	Policies           []PolicyTrace  `json:"policies"`
+}
+
+type RoleDecision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
+
+type PolicyTrace struct {
+	Policy     string           `json:"policy"`
+	Effect     string           `json:"effect"`
+	Applies    bool             `json:"applies"`
+	Matched    bool             `json:"matched"`
+	Conditions []ConditionTrace `json:"conditions"`
+}
+
+type ConditionTrace struct {
+	Condition string `json:"condition"`
+	Result    bool   `json:"result"`
+}
+
+func ProviderAccessExplanation2Server(explanation auth.AccessExplanation) AccessExplanation {
+	policies := make([]PolicyTrace, 0, len(explanation.Policies))
+	for _, trace := range explanation.Policies {
+		conditions := make([]ConditionTrace, 0, len(trace.Conditions))
+		for _, condition := range trace.Conditions {
+			conditions = append(conditions, ConditionTrace(condition))
+		}
+		policies = append(policies, PolicyTrace{
+			Policy:     trace.Policy,
+			Effect:     trace.Effect,
+			Applies:    trace.Applies,
+			Matched:    trace.Matched,
+			Conditions: conditions,
+		})
+	}
+
+	return AccessExplanation{
+		AccessDecision:     AccessDecision(explanation.AccessDecision),
+		Roles:              RoleDecision{Allowed: explanation.RoleAllowed, Reason: explanation.RoleReason},
+		SubjectAttributes:  explanation.Subject,
+		ResourceAttributes: explanation.Resource,
+		Policies:           policies,
+	}
+}
+
+type TeacherGroups struct {
+	UserID string   `json:"user_id"`
+	Groups []string `json:"groups"`
+}
+
+type DeleteAccountData struct {
+	Password string `json:"password"`
+}
+
+type EmailChangeData struct {
+	Email string `json:"email"`
+}
+
+type TokenData struct {
+	Token string `json:"token"`
+}
+
+// MFA
+
+type MFAChallenge struct {
+	MFARequired bool     `json:"mfa_required"`
+	MFAToken    string   `json:"mfa_token"`
+	Methods     []string `json:"mfa_methods"`
+}
+
+// MFAEnrollmentChallenge is returned instead of tokens when the user's role
+// requires a second factor that has not been enrolled yet.
+type MFAEnrollmentChallenge struct {
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required"`
+	EnrollmentToken       string `json:"enrollment_token"`
+}
+
+type MFAEnrollmentResult struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	*Tokens
+}
+
+type ActivateKeyResult struct {
+	Status string `json:"status"`
+	*MFAEnrollmentChallenge
+}
+
+type LoginMFAData struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TOTPCodeData struct {
+	Code string `json:"code"`
+}
+
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCode          string `json:"qr_code"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RecoveryCodesCount struct {
+	UserID    string `json:"user_id"`
+	Remaining int    `json:"remaining"`
+}
+
+func ProviderTOTPEnrollment2Server(enrollment mfa.TOTPEnrollment) TOTPEnrollment {
+	return TOTPEnrollment{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+		QRCode:          qrCodeDataURI(enrollment.QRCode),
+	}
+}
+
+// Passkeys
+
+type WebAuthnChallenge struct {
+	SessionID string `json:"session_id"`
+	Options   any    `json:"options"`
+}
+
+func ProviderWebAuthnChallenge2Server(challenge mfa.WebAuthnChallenge) WebAuthnChallenge {
+	return WebAuthnChallenge{
+		SessionID: challenge.SessionID,
+		Options:   challenge.Options,
+	}
+}
+
+type FinishPasskeyRegistrationData struct {
+	SessionID  string          `json:"session_id"`
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+type FinishPasskeyAssertionData struct {
+	MFAToken   string          `json:"mfa_token,omitempty"`
+	SessionID  string          `json:"session_id"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+type Passkey struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+}
+
+func ProviderPasskey2Server(passkey mfa.Passkey) Passkey {
+	return Passkey{
+		ID:         passkey.ID,
+		Name:       passkey.Name,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt,
+	}
+}
+
+func ProviderUser2PasskeyUser(user auth.User) mfa.PasskeyUser {
+	return mfa.PasskeyUser{
+		ID:          user.ID,
+		Name:        user.Username,
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+	}
+}
+
+// Data export
+
+type DataExport struct {
+	ExportedAt      string               `json:"exported_at"`
+	User            UserRecord           `json:"user"`
+	Roles           []string             `json:"roles"`
+	Student         *Student             `json:"student,omitempty"`
+	Teacher         *Teacher             `json:"teacher,omitempty"`
+	Sessions        SessionsExport       `json:"sessions"`
+	LoginHistory    []LoginAttempt       `json:"login_history"`
+	UsernameHistory []UsernameHistory    `json:"username_history"`
+	EmailChanges    []EmailChangeRequest `json:"email_changes"`
+	ActivationKeys  []KeyRedemption      `json:"activation_keys"`
+	IssuedKeys      []KeyIssuance        `json:"issued_keys"`
+	RoleRequests    []RoleRequest        `json:"role_requests"`
+	AuditEvents     []AuditEvent         `json:"audit_events"`
+}
+
+type UserRecord struct {
+	User
+	SessionsRevokedAt string `json:"sessions_revoked_at,omitempty"`
+	DeactivatedAt     string `json:"deactivated_at,omitempty"`
+	DeletedAt         string `json:"deleted_at,omitempty"`
+}
+
+// SessionsExport describes how the user signs in. Tokens are stateless, so
+// besides the last revocation only the enrolled factors are known.
+type SessionsExport struct {
+	RevokedAt  string    `json:"revoked_at,omitempty"`
+	MFAMethods []string  `json:"mfa_methods"`
+	Passkeys   []Passkey `json:"passkeys"`
+}
+
+type LoginAttempt struct {
+	Login     string `json:"login,omitempty"`
+	Method    string `json:"method"`
+	Succeeded bool   `json:"succeeded"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type EmailChangeRequest struct {
+	OldEmail    string `json:"old_email"`
+	NewEmail    string `json:"new_email"`
+	CreatedAt   string `json:"created_at"`
+	ExpiresAt   string `json:"expires_at"`
+	ConfirmedAt string `json:"confirmed_at,omitempty"`
+	CancelledAt string `json:"cancelled_at,omitempty"`
+}
+
+type KeyRedemption struct {
+	Role       string          `json:"role"`
+	KeyID      string          `json:"key_id,omitempty"`
+	Attributes json.RawMessage `json:"attributes"`
+	RedeemedAt string          `json:"redeemed_at"`
+}
+
+type KeyIssuance struct {
+	Role       string          `json:"role"`
+	KeyID      string          `json:"key_id"`
+	Attributes json.RawMessage `json:"attributes"`
+	IssuedAt   string          `json:"issued_at"`
+}
+
+type AuditEvent struct {
+	ID        string          `json:"id"`
+	ActorID   string          `json:"actor_id,omitempty"`
+	SubjectID string          `json:"subject_id,omitempty"`
+	Action    string          `json:"action"`
+	Details   json.RawMessage `json:"details"`
+	IP        string          `json:"ip,omitempty"`
+	CreatedAt string          `json:"created_at"`
+}
+
+func ProviderDataExport2Server(export auth.DataExport) DataExport {
+	resp := DataExport{
+		User: UserRecord{
+			User:              ProviderUser2Server(export.User.User),
+			SessionsRevokedAt: export.User.SessionsRevokedAt,
+			DeactivatedAt:     export.User.DeactivatedAt,
+			DeletedAt:         export.User.DeletedAt,
+		},
+		Roles:           export.Roles,
+		Sessions:        SessionsExport{RevokedAt: export.User.SessionsRevokedAt},
+		LoginHistory:    make([]LoginAttempt, 0, len(export.LoginHistory)),
+		UsernameHistory: make([]UsernameHistory, 0, len(export.UsernameHistory)),
+		EmailChanges:    make([]EmailChangeRequest, 0, len(export.EmailChanges)),
+		ActivationKeys:  make([]KeyRedemption, 0, len(export.ActivationKeys)),
+		IssuedKeys:      make([]KeyIssuance, 0, len(export.IssuedKeys)),
+		RoleRequests:    ProviderRoleRequests2Server(export.RoleRequests),
+		AuditEvents:     make([]AuditEvent, 0, len(export.AuditEvents)),
+	}
+	if resp.Roles == nil {
+		resp.Roles = []string{}
+	}
+	if export.Student != nil {
+		student := ProviderStudent2Server(*export.Student)
+		resp.Student = &student
+	}
+	if export.Teacher != nil {
+		teacher := ProviderTeacher2Server(*export.Teacher)
+		resp.Teacher = &teacher
+	}
+	for _, attempt := range export.LoginHistory {
+		resp.LoginHistory = append(resp.LoginHistory, LoginAttempt(attempt))
+	}
+	for _, entry := range export.UsernameHistory {
+		resp.UsernameHistory = append(resp.UsernameHistory, ProviderUsernameHistory2Server(entry))
+	}
+	for _, request := range export.EmailChanges {
+		resp.EmailChanges = append(resp.EmailChanges, EmailChangeRequest(request))
+	}
+	for _, redemption := range export.ActivationKeys {
+		resp.ActivationKeys = append(resp.ActivationKeys, KeyRedemption(redemption))
+	}
+	for _, issuance := range export.IssuedKeys {
+		resp.IssuedKeys = append(resp.IssuedKeys, KeyIssuance(issuance))
+	}
+	for _, event := range export.AuditEvents {
+		resp.AuditEvents = append(resp.AuditEvents, AuditEvent(event))
+	}
+	return resp
+}
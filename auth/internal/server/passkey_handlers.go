@@ -0,0 +1,231 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/mfa"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
+)
+
+// Passkey registration...
+
+func (s *Server) beginPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getEnrollmentClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	user, err := s.authProvider.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUserNotFound):
+			s.respondWithError(w, http.StatusNotFound, "user not found")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	challenge, err := s.mfaProvider.BeginPasskeyRegistration(r.Context(), ProviderUser2PasskeyUser(user))
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ProviderWebAuthnChallenge2Server(challenge))
+}
+
+func (s *Server) finishPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getEnrollmentClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var req FinishPasskeyRegistrationData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SessionID == "" || len(req.Credential) == 0 {
+		s.respondWithError(w, http.StatusBadRequest, "session_id and credential are required")
+		return
+	}
+
+	user, err := s.authProvider.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUserNotFound):
+			s.respondWithError(w, http.StatusNotFound, "user not found")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = s.mfaProvider.FinishPasskeyRegistration(r.Context(), ProviderUser2PasskeyUser(user), req.SessionID, req.Name, req.Credential)
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	if claims.TokenType == tokens.TokenTypeEnrollment {
+		s.respondWithTokens(w, r, http.StatusCreated, claims.UserID)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusCreated, map[string]string{"status": "success"})
+}
+
+func (s *Server) listPasskeysHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	passkeys, err := s.mfaProvider.ListPasskeys(r.Context(), claims.UserID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]Passkey, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		resp = append(resp, ProviderPasskey2Server(passkey))
+	}
+
+	s.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) deletePasskeyHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	passkeyID := r.PathValue("id")
+	if passkeyID == "" {
+		s.respondWithError(w, http.StatusBadRequest, "passkey ID is required")
+		return
+	}
+
+	if err := s.mfaProvider.DeletePasskey(r.Context(), claims.UserID, passkeyID); err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// Passwordless login...
+
+func (s *Server) beginPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	challenge, err := s.mfaProvider.BeginPasskeyLogin(r.Context())
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ProviderWebAuthnChallenge2Server(challenge))
+}
+
+func (s *Server) finishPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req FinishPasskeyAssertionData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SessionID == "" || len(req.Credential) == 0 {
+		s.respondWithError(w, http.StatusBadRequest, "session_id and credential are required")
+		return
+	}
+
+	userID, err := s.mfaProvider.FinishPasskeyLogin(r.Context(), req.SessionID, req.Credential)
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	if err := s.authProvider.LoginPasskey(r.Context(), userID, clientInfo(r)); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrAccountLocked):
+			s.respondWithError(w, http.StatusUnauthorized, "invalid credentials")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	s.respondWithTokens(w, r, http.StatusCreated, userID)
+}
+
+// Passkey as a second factor...
+
+func (s *Server) beginPasskeyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var req LoginMFAData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" {
+		s.respondWithError(w, http.StatusBadRequest, "mfa_token is required")
+		return
+	}
+
+	claims, err := s.tokensProvider.ValidateMFAToken(req.MFAToken)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid mfa token")
+		return
+	}
+
+	challenge, err := s.mfaProvider.BeginPasskeyMFA(r.Context(), claims.UserID)
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ProviderWebAuthnChallenge2Server(challenge))
+}
+
+func (s *Server) finishPasskeyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var req FinishPasskeyAssertionData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
+		req.MFAToken == "" || req.SessionID == "" || len(req.Credential) == 0 {
+		s.respondWithError(w, http.StatusBadRequest, "mfa_token, session_id and credential are required")
+		return
+	}
+
+	claims, err := s.tokensProvider.ValidateMFAToken(req.MFAToken)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid mfa token")
+		return
+	}
+
+	if err := s.authProvider.CheckMFALockout(r.Context(), claims.UserID); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrAccountLocked):
+			s.respondWithError(w, http.StatusTooManyRequests, "too many failed attempts, try again later")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = s.mfaProvider.FinishPasskeyMFA(r.Context(), claims.UserID, req.SessionID, req.Credential)
+	if errors.Is(err, mfa.ErrInvalidPasskey) {
+		s.authProvider.RecordMFAAttempt(r.Context(), claims.UserID, auth.LoginMethodPasskey, false, clientInfo(r))
+	}
+	if err != nil {
+		s.respondWithMFAError(w, err)
+		return
+	}
+	s.authProvider.RecordMFAAttempt(r.Context(), claims.UserID, auth.LoginMethodPasskey, true, clientInfo(r))
+
+	s.respondWithTokens(w, r, http.StatusCreated, claims.UserID)
+}
+
+func passkeyErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidPasskey):
+		return http.StatusUnauthorized, true
+	case errors.Is(err, mfa.ErrWebAuthnSession):
+		return http.StatusBadRequest, true
+	case errors.Is(err, mfa.ErrPasskeyNotFound):
+		return http.StatusNotFound, true
+	}
+	return 0, false
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+)
+
+// Current user...
+
+func (s *Server) getMeHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	profile, err := s.authProvider.GetProfile(r.Context(), claims.UserID)
+	if err != nil {
+		s.respondWithProfileError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ProviderProfile2Server(profile))
+}
+
+func (s *Server) updateMeHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var req UpdateProfileData
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "invalid request format")
+		return
+	}
+
+	if req.FirstName == nil && req.LastName == nil && req.Degree == nil {
+		s.respondWithError(w, http.StatusBadRequest, "nothing to update")
+		return
+	}
+
+	profile, err := s.authProvider.UpdateProfile(r.Context(), claims.UserID, ServerProfileUpdate2Provider(req))
+	if err != nil {
+		s.respondWithProfileError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ProviderProfile2Server(profile))
+}
+
+// Username change...
+
+func (s *Server) changeUsernameHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var req UsernameChangeData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		s.respondWithError(w, http.StatusBadRequest, "username is required")
+		return
+	}
+
+	username, err := s.authProvider.ChangeUsername(r.Context(), claims.UserID, req.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidUsername):
+			s.respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, auth.ErrUsernameExists):
+			s.respondWithError(w, http.StatusConflict, "username exists")
+		case errors.Is(err, auth.ErrUsernameReserved):
+			s.respondWithError(w, http.StatusConflict, "username is reserved")
+		case errors.Is(err, auth.ErrUserNotFound):
+			s.respondWithError(w, http.StatusNotFound, "user not found")
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	s.audit(r, claims.UserID, claims.UserID, auth.AuditUsernameChanged, map[string]any{"username": username})
+
+	s.respondWithJSON(w, http.StatusOK, UsernameChangeData{Username: username})
+}
+
+func (s *Server) getUsernameHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := s.requirePermission(r, auth.PermUsersAudit); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		s.respondWithError(w, http.StatusBadRequest, "user ID is required")
+		return
+	}
+
+	history, err := s.authProvider.GetUsernameHistory(r.Context(), userID)
+	if err != nil {
+		s.respondWithProfileError(w, err)
+		return
+	}
+
+	resp := make([]UsernameHistory, 0, len(history))
+	for _, entry := range history {
+		resp = append(resp, ProviderUsernameHistory2Server(entry))
+	}
+	s.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) getIdentityConflictsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := s.requirePermission(r, auth.PermUsersAudit); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	conflicts, err := s.authProvider.GetIdentityConflicts(r.Context())
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]IdentityConflict, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		resp = append(resp, IdentityConflict(conflict))
+	}
+	s.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) resolveIdentityConflictHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermUsersManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	var req ConflictResolutionData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Value == "" {
+		s.respondWithError(w, http.StatusBadRequest, "value is required")
+		return
+	}
+
+	conflict, err := s.authProvider.ResolveIdentityConflict(r.Context(), r.PathValue("id"), req.Value)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrConflictNotFound):
+			s.respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrInvalidEmail):
+			s.respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, auth.ErrUsernameExists), errors.Is(err, auth.ErrEmailExists),
+			errors.Is(err, auth.ErrUsernameReserved):
+			s.respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	s.audit(r, claims.UserID, conflict.UserID, auth.AuditConflictResolved, map[string]any{
+		"field":        conflict.Field,
+		conflict.Field: conflict.Value,
+	})
+
+	s.respondWithJSON(w, http.StatusOK, IdentityConflict(conflict))
+}
+
+// Email change...
+
+func (s *Server) requestEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var req EmailChangeData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		s.respondWithError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	if err := s.authProvider.RequestEmailChange(r.Context(), claims.UserID, req.Email); err != nil {
+		s.respondWithEmailChangeError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusAccepted, map[string]string{"status": "pending"})
+}
+
+func (s *Server) confirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var req TokenData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		s.respondWithError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	userID, err := s.authProvider.ConfirmEmailChange(r.Context(), req.Token)
+	if err != nil {
+		s.respondWithEmailChangeError(w, err)
+		return
+	}
+	s.audit(r, userID, userID, auth.AuditEmailChanged, nil)
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "confirmed"})
+}
+
+func (s *Server) cancelEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var req TokenData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		s.respondWithError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	if err := s.authProvider.CancelEmailChange(r.Context(), req.Token); err != nil {
+		s.respondWithEmailChangeError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "cancelled"})
+}
+
+func (s *Server) respondWithEmailChangeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidEmail):
+		s.respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, auth.ErrEmailExists):
+		s.respondWithError(w, http.StatusConflict, "email exists")
+	case errors.Is(err, auth.ErrInvalidEmailChange):
+		s.respondWithError(w, http.StatusBadRequest, "invalid or expired token")
+	case errors.Is(err, auth.ErrUserNotFound):
+		s.respondWithError(w, http.StatusNotFound, "user not found")
+	default:
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (s *Server) respondWithProfileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidProfile):
+		s.respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, auth.ErrUserNotFound):
+		s.respondWithError(w, http.StatusNotFound, "user not found")
+	default:
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/config"
+)
+
+// rateLimiter is an in-memory sliding window limiter keyed by an arbitrary
+// string such as an email address or a client IP.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+	calls  int
+}
+
+const rateLimiterSweepEvery = 1000
+
+func newRateLimiter(conf config.RateLimit) *rateLimiter {
+	return &rateLimiter{
+		limit:  conf.Limit,
+		window: conf.Window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow records a hit for the key and reports whether it is within the limit.
+// A non-positive limit disables the limiter.
+func (l *rateLimiter) Allow(key string) bool {
+	if l.limit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.calls++
+	if l.calls%rateLimiterSweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	hits := pruneHits(l.hits[key], now.Add(-l.window))
+	if len(hits) >= l.limit {
+		l.hits[key] = hits
+		return false
+	}
+
+	l.hits[key] = append(hits, now)
+	return true
+}
+
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, hits := range l.hits {
+		if hits = pruneHits(hits, now.Add(-l.window)); len(hits) == 0 {
+			delete(l.hits, key)
+		} else {
+			l.hits[key] = hits
+		}
+	}
+}
+
+func pruneHits(hits []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(since) {
+		i++
+	}
+	return hits[i:]
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+)
+
+// Roles and permissions...
+
+func (s *Server) getRolesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := s.requirePermission(r, auth.PermRolesManage); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	roles, err := s.authProvider.GetRoles(r.Context())
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]Role, 0, len(roles))
+	for _, role := range roles {
+		resp = append(resp, Role(role))
+	}
+	s.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) createRoleHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermRolesManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	var req CreateRoleData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		s.respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	if err := s.authProvider.CreateRole(r.Context(), req.Name, req.Description); err != nil {
+		s.respondWithRoleError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, "", auth.AuditRoleCreated, map[string]any{"role": req.Name})
+
+	s.respondWithJSON(w, http.StatusCreated, Role{Name: req.Name, Description: req.Description, Permissions: []string{}})
+}
+
+func (s *Server) deleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermRolesManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	role := r.PathValue("name")
+	if err := s.authProvider.DeleteRole(r.Context(), role); err != nil {
+		s.respondWithRoleError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, "", auth.AuditRoleDeleted, map[string]any{"role": role})
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (s *Server) getPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := s.requirePermission(r, auth.PermRolesManage); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	permissions, err := s.authProvider.GetPermissions(r.Context())
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		resp = append(resp, Permission(permission))
+	}
+	s.respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) grantPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermRolesManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	role, permission := r.PathValue("name"), r.PathValue("permission")
+	if err := s.authProvider.GrantPermission(r.Context(), role, permission); err != nil {
+		s.respondWithRoleError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, "", auth.AuditPermissionGranted, map[string]any{"role": role, "permission": permission})
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "granted"})
+}
+
+func (s *Server) revokePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	claims, status, err := s.requirePermission(r, auth.PermRolesManage)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	role, permission := r.PathValue("name"), r.PathValue("permission")
+	if err := s.authProvider.RevokePermission(r.Context(), role, permission); err != nil {
+		s.respondWithRoleError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, "", auth.AuditPermissionRevoked, map[string]any{"role": role, "permission": permission})
+
+	s.respondWithJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+// Role assignments...
+
+// grantRoleHandler assigns a role to a user within a scope. Managing roles of
+// a university or group is itself a scoped permission, so a university admin
+// can grant roles inside their university only.
+func (s *Server) grantRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var req GrantRoleData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+		s.respondWithError(w, http.StatusBadRequest, "role is required")
+		return
+	}
+	if req.ScopeType == "" {
+		req.ScopeType = auth.ScopeGlobal
+	}
+
+	scope := auth.RoleScope{Type: req.ScopeType, ID: req.ScopeID}
+	claims, status, err := s.requireScopedPermission(r, auth.PermRolesManage, scopeResource(scope))
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	userID := r.PathValue("id")
+	if err := s.authProvider.GrantRole(r.Context(), userID, req.Role, scope, claims.UserID, expiresAt); err != nil {
+		s.respondWithRoleError(w, err)
+		return
+	}
+	details := map[string]any{
+		"role":       req.Role,
+		"scope_type": scope.Type,
+		"scope_id":   scope.ID,
+	}
+	if req.ExpiresAt != nil {
+		details["expires_at"] = expiresAt
+	}
+	s.audit(r, claims.UserID, userID, auth.AuditRoleGranted, details)
+
+	resp := RoleAssignment{Role: req.Role, ScopeType: scope.Type, ScopeID: scope.ID, GrantedBy: claims.UserID}
+	if req.ExpiresAt != nil {
+		resp.ExpiresAt = expiresAt.Format(time.RFC3339)
+	}
+	s.respondWithJSON(w, http.StatusCreated, resp)
+}
+
+// revokeRoleHandler takes a role away from a user, in one scope or in all of
+// them when no scope is given. Revoking in all scopes needs roles:manage
+// globally. A reason is mandatory and ends up in the audit log.
+func (s *Server) revokeRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var req RevokeRoleData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		s.respondWithError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+
+	var scope *auth.RoleScope
+	var resource auth.Resource
+	if req.ScopeType != "" {
+		scope = &auth.RoleScope{Type: req.ScopeType, ID: req.ScopeID}
+		resource = scopeResource(*scope)
+	}
+
+	claims, status, err := s.requireScopedPermission(r, auth.PermRolesManage, resource)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	userID, role := r.PathValue("id"), r.PathValue("role")
+	revoked, err := s.authProvider.RevokeRole(r.Context(), userID, role, scope)
+	if err != nil {
+		s.respondWithRoleError(w, err)
+		return
+	}
+
+	resp := RevokedRoles{UserID: userID, Role: role, Reason: req.Reason, Revoked: make([]RoleAssignment, 0, len(revoked))}
+	for _, assignment := range revoked {
+		resp.Revoked = append(resp.Revoked, ProviderRoleAssignment2Server(assignment))
+	}
+	s.audit(r, claims.UserID, userID, auth.AuditRoleRevoked, map[string]any{
+		"role":        role,
+		"reason":      req.Reason,
+		"assignments": resp.Revoked,
+	})
+
+	s.respondWithJSON(w, http.StatusOK, resp)
+}
+
+// scopeResource is the resource a caller must be allowed to manage in order
+// to hand out or take away roles in the scope.
+func scopeResource(scope auth.RoleScope) auth.Resource {
+	switch scope.Type {
+	case auth.ScopeUniversity:
+		return auth.Resource{UniversityID: scope.ID}
+	case auth.ScopeGroup:
+		return auth.Resource{GroupID: scope.ID}
+	default:
+		return auth.Resource{}
+	}
+}
+
+// Effective permissions...
+
+func (s *Server) getMyPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	s.respondWithPermissions(w, r, claims.UserID)
+}
+
+func (s *Server) getUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := s.requirePermission(r, auth.PermRolesRead); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	s.respondWithPermissions(w, r, r.PathValue("id"))
+}
+
+func (s *Server) respondWithPermissions(w http.ResponseWriter, r *http.Request, userID string) {
+	permissions, err := s.authProvider.GetUserPermissions(r.Context(), userID)
+	if err != nil {
+		s.respondWithRoleError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ProviderEffectivePermissions2Server(userID, permissions))
+}
+
+func (s *Server) respondWithRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidRole), errors.Is(err, auth.ErrInvalidScope),
+		errors.Is(err, auth.ErrInvalidExpiry):
+		s.respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, auth.ErrRoleNotFound), errors.Is(err, auth.ErrPermissionNotFound),
+		errors.Is(err, auth.ErrUserNotFound), errors.Is(err, auth.ErrRoleNotGranted):
+		s.respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, auth.ErrRoleExists), errors.Is(err, auth.ErrBuiltinRole),
+		errors.Is(err, auth.ErrRoleAlreadyGranted), errors.Is(err, auth.ErrRoleRequiresActivation):
+		s.respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
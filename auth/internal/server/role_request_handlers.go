@@ -0,0 +1,180 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
+)
+
+// Role requests...
+
+func (s *Server) requestRoleHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	var req RoleRequestData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "invalid request format")
+		return
+	}
+
+	request, err := s.authProvider.RequestRole(r.Context(), auth.RoleRequest{
+		UserID:         claims.UserID,
+		Role:           req.Role,
+		UniversityID:   req.UniversityID,
+		GroupID:        req.GroupID,
+		EnrollmentYear: req.EnrollmentYear,
+		Degree:         req.Degree,
+		Comment:        req.Comment,
+	})
+	if err != nil {
+		s.respondWithRoleRequestError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, claims.UserID, auth.AuditRoleRequested, map[string]any{
+		"request_id":    request.ID,
+		"role":          request.Role,
+		"university_id": request.UniversityID,
+	})
+
+	s.respondWithJSON(w, http.StatusCreated, RoleRequest(request))
+}
+
+func (s *Server) getMyRoleRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.getClaimsFromRequest(r)
+	if err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	requests, err := s.authProvider.GetUserRoleRequests(r.Context(), claims.UserID)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ProviderRoleRequests2Server(requests))
+}
+
+// getRoleRequestsHandler lists role requests to approvers. Approvers scoped
+// to a university have to filter by it.
+func (s *Server) getRoleRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	universityID := query.Get("university_id")
+
+	if _, status, err := s.requireScopedPermission(r, auth.PermRolesApprove, auth.Resource{UniversityID: universityID}); err != nil {
+		s.respondWithError(w, status, err.Error())
+		return
+	}
+
+	requests, err := s.authProvider.GetRoleRequests(r.Context(), query.Get("status"), universityID)
+	if err != nil {
+		s.respondWithRoleRequestError(w, err)
+		return
+	}
+
+	s.respondWithJSON(w, http.StatusOK, ProviderRoleRequests2Server(requests))
+}
+
+func (s *Server) approveRoleRequestHandler(w http.ResponseWriter, r *http.Request) {
+	claims, req, ok := s.reviewRoleRequest(w, r)
+	if !ok {
+		return
+	}
+
+	request, err := s.authProvider.ApproveRoleRequest(r.Context(), r.PathValue("id"), claims.UserID, req.Comment)
+	if err != nil {
+		s.respondWithRoleRequestError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, request.UserID, auth.AuditRoleRequestApproved, map[string]any{
+		"request_id":    request.ID,
+		"role":          request.Role,
+		"university_id": request.UniversityID,
+		"comment":       request.ReviewComment,
+	})
+
+	s.respondWithJSON(w, http.StatusOK, RoleRequest(request))
+}
+
+func (s *Server) rejectRoleRequestHandler(w http.ResponseWriter, r *http.Request) {
+	claims, req, ok := s.reviewRoleRequest(w, r)
+	if !ok {
+		return
+	}
+
+	request, err := s.authProvider.RejectRoleRequest(r.Context(), r.PathValue("id"), claims.UserID, req.Comment)
+	if err != nil {
+		s.respondWithRoleRequestError(w, err)
+		return
+	}
+	s.audit(r, claims.UserID, request.UserID, auth.AuditRoleRequestRejected, map[string]any{
+		"request_id":    request.ID,
+		"role":          request.Role,
+		"university_id": request.UniversityID,
+		"comment":       request.ReviewComment,
+	})
+
+	s.respondWithJSON(w, http.StatusOK, RoleRequest(request))
+}
+
+// reviewRoleRequest authorizes a decision on the request in the path. The
+// caller needs roles:approve in the requested university or group. Only
+// global approvers learn that a request does not exist, everyone else gets
+// the same 403 as for a request outside their scope.
+func (s *Server) reviewRoleRequest(w http.ResponseWriter, r *http.Request) (*tokens.Claims, ReviewRoleRequestData, bool) {
+	if _, err := s.getClaimsFromRequest(r); err != nil {
+		s.respondWithError(w, http.StatusUnauthorized, "invalid token")
+		return nil, ReviewRoleRequestData{}, false
+	}
+
+	var req ReviewRoleRequestData
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.respondWithError(w, http.StatusBadRequest, "invalid request format")
+		return nil, ReviewRoleRequestData{}, false
+	}
+
+	request, err := s.authProvider.GetRoleRequest(r.Context(), r.PathValue("id"))
+	if errors.Is(err, auth.ErrRoleRequestNotFound) {
+		if _, status, err := s.requirePermission(r, auth.PermRolesApprove); err != nil {
+			s.respondWithError(w, status, err.Error())
+			return nil, ReviewRoleRequestData{}, false
+		}
+	}
+	if err != nil {
+		s.respondWithRoleRequestError(w, err)
+		return nil, ReviewRoleRequestData{}, false
+	}
+
+	resource := auth.Resource{UniversityID: request.UniversityID, GroupID: request.GroupID}
+	claims, status, err := s.requireScopedPermission(r, auth.PermRolesApprove, resource)
+	if err != nil {
+		s.respondWithError(w, status, err.Error())
+		return nil, ReviewRoleRequestData{}, false
+	}
+
+	return claims, req, true
+}
+
+func (s *Server) respondWithRoleRequestError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidRoleRequest):
+		s.respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, auth.ErrSelfReview):
+		s.respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, auth.ErrRoleRequestNotFound), errors.Is(err, auth.ErrUserNotFound):
+		s.respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, auth.ErrRoleRequestPending), errors.Is(err, auth.ErrRoleRequestReviewed),
+		errors.Is(err, auth.ErrRoleAlreadyActivated):
+		s.respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		s.respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
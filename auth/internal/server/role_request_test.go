@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/config"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
+	"github.com/vladlim/auth-service-practice/auth/internal/repository/models"
+)
+
+const (
+	testRequestID    = "9a7c4e21-5d3b-4f80-b6e2-1c8d9f0a3b47"
+	testMissingID    = "e3b5a0d9-8c16-4a72-9f4e-0d2c6b1a7e58"
+	testUniversityID = "4d1f0c7e-2a6b-4e3d-9f58-b1c2d3e4f5a6"
+	testOtherUniID   = "71e2c9b4-0a5d-4c3f-8e16-b9d0a2f4c6e3"
+)
+
+// roleRequestRepository knows one request, in testUniversityID. testAdminID
+// approves globally, testUserID only in testOtherUniID.
+type roleRequestRepository struct {
+	auth.Repository
+}
+
+func (roleRequestRepository) HasPermission(ctx context.Context, userID, permission, universityID, groupID string) (bool, error) {
+	return userID == testAdminID || universityID == testOtherUniID, nil
+}
+
+func (roleRequestRepository) GetRoleRequest(ctx context.Context, requestID string) (models.RoleRequest, error) {
+	if requestID != testRequestID {
+		return models.RoleRequest{}, sql.ErrNoRows
+	}
+	return models.RoleRequest{ID: testRequestID, UserID: "c58f2d1e-6b49-4a07-93d8-e4a1b7c0f962", Role: "student", UniversityID: testUniversityID}, nil
+}
+
+func TestReviewRoleRequestHidesRequestsOutsideScope(t *testing.T) {
+	s := newTestServer(t)
+	s.authProvider = auth.New(roleRequestRepository{}, nil, nil, nil, config.Config{})
+	s.tokensProvider = tokens.New(activeSessions{})
+
+	tests := []struct {
+		name      string
+		userID    string
+		requestID string
+		want      int
+	}{
+		{"scoped approver, missing request", testUserID, testMissingID, http.StatusForbidden},
+		{"scoped approver, request in another university", testUserID, testRequestID, http.StatusForbidden},
+		{"global approver, missing request", testAdminID, testMissingID, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := s.tokensProvider.GenerateAccessToken(tt.userID, nil)
+			if err != nil {
+				t.Fatalf("GenerateAccessToken: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/admin/role-requests/"+tt.requestID+"/approve", nil)
+			req.SetPathValue("id", tt.requestID)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+
+			s.approveRoleRequestHandler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/vladlim/auth-service-practice/auth/internal/config"
 	"github.com/vladlim/auth-service-practice/auth/internal/providers/auth"
+	"github.com/vladlim/auth-service-practice/auth/internal/providers/mfa"
 	"github.com/vladlim/auth-service-practice/auth/internal/providers/tokens"
 )
 
@@ -31,14 +32,28 @@ type Server struct {
 	server         http.Server
 	authProvider   auth.AuthProvider
 	tokensProvider tokens.TokensProvider
+	mfaProvider    mfa.MFAProvider
+	emailLogin     emailLogin
 }
 
-func New(conf config.Config, authProvider auth.AuthProvider, tokensProvider tokens.TokensProvider) *Server {
+type emailLogin struct {
+	enabled    bool
+	perAddress *rateLimiter
+	perIP      *rateLimiter
+}
+
+func New(conf config.Config, authProvider auth.AuthProvider, tokensProvider tokens.TokensProvider, mfaProvider mfa.MFAProvider) *Server {
 	s := new(Server)
 	s.server.Addr = fmt.Sprintf(":%d", conf.Port)
-	s.server.Handler = s.setRouter()
+	s.server.Handler = s.rejectImpersonation(s.setRouter())
 	s.authProvider = authProvider
 	s.tokensProvider = tokensProvider
+	s.mfaProvider = mfaProvider
+	s.emailLogin = emailLogin{
+		enabled:    conf.Security.EmailLogin.Enabled,
+		perAddress: newRateLimiter(conf.Security.EmailLogin.PerAddress),
+		perIP:      newRateLimiter(conf.Security.EmailLogin.PerIP),
+	}
 	return s
 }
 
@@ -49,8 +64,63 @@ func (s *Server) setRouter() *http.ServeMux {
 	mux.HandleFunc("POST /auth/register", s.registerUserHandler)
 	mux.HandleFunc("POST /auth/login", s.loginUserHandler)
 	mux.HandleFunc("POST /auth/refresh", s.refreshTokenHandler)
+	mux.HandleFunc("POST /auth/login/mfa", s.loginMFAHandler)
+	mux.HandleFunc("POST /auth/login/email", s.requestEmailLoginHandler)
+	mux.HandleFunc("POST /auth/login/email/verify", s.verifyEmailLoginHandler)
+	mux.HandleFunc("POST /auth/login/mfa/passkey/begin", s.beginPasskeyMFAHandler)
+	mux.HandleFunc("POST /auth/login/mfa/passkey/finish", s.finishPasskeyMFAHandler)
+	mux.HandleFunc("POST /auth/passkey/login/begin", s.beginPasskeyLoginHandler)
+	mux.HandleFunc("POST /auth/passkey/login/finish", s.finishPasskeyLoginHandler)
+
+	mux.HandleFunc("GET /users/me", s.getMeHandler)
+	mux.HandleFunc("PATCH /users/me", s.updateMeHandler)
+	mux.HandleFunc("DELETE /users/me", s.deleteMeHandler)
+	mux.HandleFunc("POST /users/me/deactivate", s.deactivateMeHandler)
+	mux.HandleFunc("GET /users/me/export", s.exportMeHandler)
+	mux.HandleFunc("PATCH /users/me/username", s.changeUsernameHandler)
+	mux.HandleFunc("POST /users/me/email", s.requestEmailChangeHandler)
+	mux.HandleFunc("POST /auth/email/confirm", s.confirmEmailChangeHandler)
+	mux.HandleFunc("POST /auth/email/cancel", s.cancelEmailChangeHandler)
+	mux.HandleFunc("POST /users/me/mfa/totp/enroll", s.enrollTOTPHandler)
+	mux.HandleFunc("POST /users/me/mfa/totp/verify", s.verifyTOTPHandler)
+	mux.HandleFunc("POST /users/me/mfa/totp/disable", s.disableTOTPHandler)
+	mux.HandleFunc("POST /users/me/mfa/recovery-codes", s.regenerateRecoveryCodesHandler)
+	mux.HandleFunc("GET /admin/users/{id}/mfa/recovery-codes", s.countRecoveryCodesHandler)
+	mux.HandleFunc("GET /admin/users/{id}/username-history", s.getUsernameHistoryHandler)
+	mux.HandleFunc("GET /admin/identity-conflicts", s.getIdentityConflictsHandler)
+	mux.HandleFunc("POST /admin/identity-conflicts/{id}/resolve", s.resolveIdentityConflictHandler)
+	mux.HandleFunc("POST /admin/users/{id}/deactivate", s.deactivateUserHandler)
+	mux.HandleFunc("POST /admin/users/{id}/reactivate", s.reactivateUserHandler)
+	mux.HandleFunc("DELETE /admin/users/{id}", s.deleteUserHandler)
+	mux.HandleFunc("GET /admin/users/{id}/export", s.exportUserHandler)
+	mux.HandleFunc("GET /admin/users/{id}/permissions", s.getUserPermissionsHandler)
+	mux.HandleFunc("POST /admin/users/{id}/roles", s.grantRoleHandler)
+	mux.HandleFunc("DELETE /admin/users/{id}/roles/{role}", s.revokeRoleHandler)
+	mux.HandleFunc("POST /admin/users/{id}/impersonate", s.impersonateHandler)
+	mux.HandleFunc("POST /users/me/role-requests", s.requestRoleHandler)
+	mux.HandleFunc("GET /users/me/role-requests", s.getMyRoleRequestsHandler)
+	mux.HandleFunc("GET /admin/role-requests", s.getRoleRequestsHandler)
+	mux.HandleFunc("POST /admin/role-requests/{id}/approve", s.approveRoleRequestHandler)
+	mux.HandleFunc("POST /admin/role-requests/{id}/reject", s.rejectRoleRequestHandler)
+	mux.HandleFunc("GET /users/me/permissions", s.getMyPermissionsHandler)
+	mux.HandleFunc("GET /admin/roles", s.getRolesHandler)
+	mux.HandleFunc("POST /admin/roles", s.createRoleHandler)
+	mux.HandleFunc("DELETE /admin/roles/{name}", s.deleteRoleHandler)
+	mux.HandleFunc("PUT /admin/roles/{name}/permissions/{permission}", s.grantPermissionHandler)
+	mux.HandleFunc("DELETE /admin/roles/{name}/permissions/{permission}", s.revokePermissionHandler)
+	mux.HandleFunc("GET /admin/permissions", s.getPermissionsHandler)
+	mux.HandleFunc("GET /admin/teachers/{id}/groups", s.getTeacherGroupsHandler)
+	mux.HandleFunc("PUT /admin/teachers/{id}/groups/{group_id}", s.assignTeacherGroupHandler)
+	mux.HandleFunc("DELETE /admin/teachers/{id}/groups/{group_id}", s.unassignTeacherGroupHandler)
+	mux.HandleFunc("POST /authz/check", s.checkAccessHandler)
+	mux.HandleFunc("POST /authz/explain", s.explainAccessHandler)
+	mux.HandleFunc("POST /users/me/passkeys/register/begin", s.beginPasskeyRegistrationHandler)
+	mux.HandleFunc("POST /users/me/passkeys/register/finish", s.finishPasskeyRegistrationHandler)
+	mux.HandleFunc("GET /users/me/passkeys", s.listPasskeysHandler)
+	mux.HandleFunc("DELETE /users/me/passkeys/{id}", s.deletePasskeyHandler)
 
 	mux.HandleFunc("POST /admin/generate-key", s.generateKeyHandler)
+	mux.HandleFunc("POST /teachers/me/keys", s.generateGroupKeyHandler)
 	mux.HandleFunc("POST /auth/activate-key", s.activateKeyHandler)
 	mux.HandleFunc("GET /users/{id}", s.getUserByIdHandler)
 	mux.HandleFunc("GET /users/email/{email}", s.getUserByEmailHandler)
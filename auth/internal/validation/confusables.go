@@ -0,0 +1,74 @@
+package validation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// confusables maps characters that render like latin letters or digits to
+// the character they imitate. It covers the Cyrillic and Greek look-alikes
+// and the digits commonly swapped for letters, which is enough to catch
+// attempts like "аdmin" (Cyrillic а) or "r00t".
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'i', 'ї': 'i',
+	'ј': 'j', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's',
+	'т': 't', 'ѵ': 'v', 'ԝ': 'w', 'х': 'x', 'у': 'y', 'ү': 'y', 'ԁ': 'd',
+	'ɡ': 'g', 'ӏ': 'l',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'γ': 'y', 'ω': 'w',
+	// Digits and symbols
+	'0': 'o', '1': 'l', '3': 'e', '5': 's', '7': 't', '|': 'l',
+}
+
+// skeleton reduces a name to the form that is compared against reserved
+// names: lowercased, look-alikes replaced and separators dropped, so that
+// "Ad.Min", "r00t" and "аdmin" all collide with their reserved originals.
+func skeleton(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r == '.' || r == '_' || r == '-' {
+			continue
+		}
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	// 'i' and 'l' are indistinguishable in many fonts.
+	return strings.ReplaceAll(s, "i", "l")
+}
+
+// mixedScripts reports whether the name combines letters of more than one
+// script. Single-script names are fine even outside latin, mixing is almost
+// always an attempt to imitate another name.
+func mixedScripts(name string) bool {
+	var script *unicode.RangeTable
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		current := scriptOf(r)
+		if script != nil && current != script {
+			return true
+		}
+		script = current
+	}
+	return false
+}
+
+var scripts = []*unicode.RangeTable{
+	unicode.Latin, unicode.Cyrillic, unicode.Greek, unicode.Armenian,
+	unicode.Hebrew, unicode.Arabic, unicode.Han,
+}
+
+func scriptOf(r rune) *unicode.RangeTable {
+	for _, table := range scripts {
+		if unicode.Is(table, r) {
+			return table
+		}
+	}
+	return nil
+}
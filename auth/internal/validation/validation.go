@@ -0,0 +1,209 @@
+package validation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vladlim/auth-service-practice/auth/internal/config"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	defaultMinUsernameLength = 3
+	defaultMaxUsernameLength = 32
+	defaultUsernameCharset   = "a-zA-Z0-9._-"
+
+	maxEmailLength      = 254
+	maxEmailLocalLength = 64
+	maxDomainLabel      = 63
+)
+
+var (
+	ErrUsernameLength     = errors.New("username has invalid length")
+	ErrUsernameCharset    = errors.New("username contains characters that are not allowed")
+	ErrUsernameReserved   = errors.New("username is reserved")
+	ErrUsernameConfusable = errors.New("username mixes look-alike characters from different scripts")
+	ErrEmailSyntax        = errors.New("email is not a valid address")
+	ErrEmailDisposable    = errors.New("disposable email domains are not allowed")
+)
+
+// defaultReserved are always reserved on top of the configured names.
+var defaultReserved = []string{
+	"admin", "administrator", "root", "system", "support", "help",
+	"security", "abuse", "postmaster", "webmaster", "noreply", "no-reply",
+	"api", "auth", "login", "register", "me", "null", "undefined",
+}
+
+// Validator checks and normalizes usernames and emails before they reach the
+// database.
+type Validator struct {
+	minLength  int
+	maxLength  int
+	charset    *regexp.Regexp
+	reserved   map[string]struct{}
+	disposable map[string]struct{}
+}
+
+func New(conf config.Security) (*Validator, error) {
+	v := &Validator{
+		minLength: conf.Username.MinLength,
+		maxLength: conf.Username.MaxLength,
+		reserved:  make(map[string]struct{}),
+	}
+	if v.minLength <= 0 {
+		v.minLength = defaultMinUsernameLength
+	}
+	if v.maxLength <= 0 {
+		v.maxLength = defaultMaxUsernameLength
+	}
+	if v.minLength > v.maxLength {
+		return nil, fmt.Errorf("username min_length %d exceeds max_length %d", v.minLength, v.maxLength)
+	}
+
+	charset := conf.Username.Charset
+	if charset == "" {
+		charset = defaultUsernameCharset
+	}
+	re, err := regexp.Compile("^[" + charset + "]+$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid username charset: %w", err)
+	}
+	v.charset = re
+
+	for _, name := range append(defaultReserved, conf.Username.Reserved...) {
+		v.reserved[skeleton(norm.NFKC.String(name))] = struct{}{}
+	}
+
+	if path := conf.Email.DisposableDomainsPath; path != "" {
+		v.disposable, err = loadDomains(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load disposable domains: %w", err)
+		}
+	}
+
+	return v, nil
+}
+
+// Username returns the NFKC-normalized username. Names that only differ from
+// a reserved one by case, separators or look-alike characters are rejected as
+// well.
+func (v *Validator) Username(username string) (string, error) {
+	username = norm.NFKC.String(strings.TrimSpace(username))
+
+	if n := utf8.RuneCountInString(username); n < v.minLength || n > v.maxLength {
+		return "", fmt.Errorf("%w: must be %d to %d characters long", ErrUsernameLength, v.minLength, v.maxLength)
+	}
+
+	// '@' is never allowed, whatever the charset says: LoginUser treats such
+	// logins as emails.
+	if strings.ContainsFunc(username, func(r rune) bool {
+		return r == '@' || unicode.IsControl(r) || unicode.IsSpace(r)
+	}) || !v.charset.MatchString(username) {
+		return "", ErrUsernameCharset
+	}
+	if first, _ := utf8.DecodeRuneInString(username); !unicode.IsLetter(first) && !unicode.IsDigit(first) {
+		return "", fmt.Errorf("%w: must start with a letter or digit", ErrUsernameCharset)
+	}
+
+	if mixedScripts(username) {
+		return "", ErrUsernameConfusable
+	}
+
+	if _, ok := v.reserved[skeleton(username)]; ok {
+		return "", ErrUsernameReserved
+	}
+
+	return username, nil
+}
+
+// Email returns the address with its domain lowercased. Only bare addresses
+// are accepted, display names and comments are not.
+func (v *Validator) Email(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", ErrEmailSyntax
+	}
+	if len(email) > maxEmailLength {
+		return "", fmt.Errorf("%w: must be at most %d characters long", ErrEmailSyntax, maxEmailLength)
+	}
+
+	at := strings.LastIndexByte(email, '@')
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+	if len(local) > maxEmailLocalLength {
+		return "", fmt.Errorf("%w: local part must be at most %d characters long", ErrEmailSyntax, maxEmailLocalLength)
+	}
+	if !validDomain(domain) {
+		return "", fmt.Errorf("%w: invalid domain", ErrEmailSyntax)
+	}
+
+	if v.isDisposable(domain) {
+		return "", ErrEmailDisposable
+	}
+
+	return local + "@" + domain, nil
+}
+
+func (v *Validator) isDisposable(domain string) bool {
+	if len(v.disposable) == 0 {
+		return false
+	}
+	for {
+		if _, ok := v.disposable[domain]; ok {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// validDomain accepts dotted hostnames only. Address literals such as
+// user@[127.0.0.1] and single-label domains are valid per RFC 5322 but are
+// never deliverable for our users.
+func validDomain(domain string) bool {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > maxDomainLabel || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r > unicode.MaxASCII && unicode.IsLetter(r)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func loadDomains(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path) // nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	domains := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[line] = struct{}{}
+	}
+	return domains, scanner.Err()
+}
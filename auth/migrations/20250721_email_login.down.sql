@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_login_tokens;
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,26 @@
+CREATE TABLE login_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    login TEXT NOT NULL,
+    method TEXT NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    ip TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX login_attempts_user_id_created_at_idx ON login_attempts (user_id, created_at);
+
+CREATE TABLE email_login_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method TEXT NOT NULL,
+    secret_hash TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX email_login_tokens_secret_hash_idx ON email_login_tokens (secret_hash);
+CREATE INDEX email_login_tokens_user_id_idx ON email_login_tokens (user_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_change_requests;
+ALTER TABLE users DROP COLUMN IF EXISTS sessions_revoked_at;
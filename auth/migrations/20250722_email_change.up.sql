@@ -0,0 +1,18 @@
+ALTER TABLE users ADD COLUMN sessions_revoked_at TIMESTAMP;
+
+CREATE TABLE email_change_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email TEXT NOT NULL,
+    new_email TEXT NOT NULL,
+    confirm_hash TEXT NOT NULL,
+    cancel_hash TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX email_change_requests_user_id_idx ON email_change_requests (user_id);
+CREATE UNIQUE INDEX email_change_requests_confirm_hash_idx ON email_change_requests (confirm_hash);
+CREATE UNIQUE INDEX email_change_requests_cancel_hash_idx ON email_change_requests (cancel_hash);
@@ -0,0 +1,10 @@
+CREATE TABLE username_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username TEXT NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT now(),
+    reserved_until TIMESTAMP NOT NULL
+);
+
+CREATE INDEX username_history_user_id_idx ON username_history (user_id, changed_at);
+CREATE INDEX username_history_username_idx ON username_history (username, reserved_until);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_deleted_at_idx;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS erased_at,
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS deactivated_at;
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN deactivated_at TIMESTAMP,
+    ADD COLUMN deleted_at TIMESTAMP,
+    ADD COLUMN erased_at TIMESTAMP;
+
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL AND erased_at IS NULL;
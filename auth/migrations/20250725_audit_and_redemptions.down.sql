@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS activation_key_redemptions;
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,24 @@
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    subject_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    ip TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_subject_id_idx ON audit_events (subject_id, created_at);
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, created_at);
+
+CREATE TABLE activation_key_redemptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    key_id TEXT,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    redeemed_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX activation_key_redemptions_user_id_idx ON activation_key_redemptions (user_id);
+CREATE INDEX activation_key_redemptions_key_id_idx ON activation_key_redemptions (key_id);
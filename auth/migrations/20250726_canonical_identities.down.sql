@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS identity_conflicts;
+
+DROP INDEX IF EXISTS users_email_canonical_key;
+DROP INDEX IF EXISTS users_username_canonical_key;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS email_canonical,
+    DROP COLUMN IF EXISTS username_canonical;
@@ -0,0 +1,53 @@
+-- Usernames and emails are unique in their canonical form: NFKC-normalized
+-- and lowercased. The canonical columns are what all lookups go through.
+ALTER TABLE users
+    ADD COLUMN username_canonical TEXT,
+    ADD COLUMN email_canonical TEXT;
+
+-- Accounts that collide with an older one in canonical form. They keep their
+-- value but get no canonical column until the user changes it, so they can
+-- not be found under it. Open conflicts are listed for admins.
+CREATE TABLE identity_conflicts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    field TEXT NOT NULL CHECK (field IN ('username', 'email')),
+    value TEXT NOT NULL,
+    kept_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    detected_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+WITH ranked AS (
+    SELECT id, username,
+           first_value(id) OVER w AS kept_user_id,
+           row_number() OVER w AS n
+    FROM users
+    WINDOW w AS (PARTITION BY lower(normalize(username, NFKC)) ORDER BY created_at, id)
+)
+INSERT INTO identity_conflicts (user_id, field, value, kept_user_id)
+SELECT id, 'username', username, kept_user_id FROM ranked WHERE n > 1;
+
+WITH ranked AS (
+    SELECT id, email,
+           first_value(id) OVER w AS kept_user_id,
+           row_number() OVER w AS n
+    FROM users
+    WINDOW w AS (PARTITION BY lower(normalize(email, NFKC)) ORDER BY created_at, id)
+)
+INSERT INTO identity_conflicts (user_id, field, value, kept_user_id)
+SELECT id, 'email', email, kept_user_id FROM ranked WHERE n > 1;
+
+UPDATE users u
+SET username_canonical = lower(normalize(u.username, NFKC))
+WHERE NOT EXISTS (
+    SELECT 1 FROM identity_conflicts c WHERE c.user_id = u.id AND c.field = 'username'
+);
+
+UPDATE users u
+SET email_canonical = lower(normalize(u.email, NFKC))
+WHERE NOT EXISTS (
+    SELECT 1 FROM identity_conflicts c WHERE c.user_id = u.id AND c.field = 'email'
+);
+
+CREATE UNIQUE INDEX users_username_canonical_key ON users (username_canonical);
+CREATE UNIQUE INDEX users_email_canonical_key ON users (email_canonical);
+CREATE INDEX identity_conflicts_user_id_idx ON identity_conflicts (user_id);
@@ -0,0 +1,2 @@
+-- Set on registration when the email domain maps to a university.
+ALTER TABLE users ADD COLUMN university_id UUID;
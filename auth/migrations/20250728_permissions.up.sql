@@ -0,0 +1,38 @@
+ALTER TABLE roles ADD COLUMN description TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE permissions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id UUID NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'Read user records'),
+    ('users:read:email', 'Look users up by email'),
+    ('users:manage', 'Deactivate, reactivate and delete accounts'),
+    ('users:export', 'Export the personal data of any user'),
+    ('users:audit', 'Read username history, identity conflicts and MFA state of users'),
+    ('students:read', 'Read student profiles'),
+    ('students:read:group', 'List the students of a group'),
+    ('teachers:read', 'Read teacher profiles'),
+    ('teachers:read:university', 'List the teachers of a university'),
+    ('roles:read', 'Read role assignments and effective permissions of users'),
+    ('roles:manage', 'Manage roles and their permissions'),
+    ('keys:generate', 'Generate activation keys');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON
+    r.name = 'admin'
+    OR (r.name = 'teacher' AND p.name IN (
+        'users:read', 'students:read', 'students:read:group',
+        'teachers:read', 'teachers:read:university', 'roles:read'))
+    OR (r.name = 'student' AND p.name IN (
+        'users:read', 'students:read:group', 'teachers:read', 'teachers:read:university'));
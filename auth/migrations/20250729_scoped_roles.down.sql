@@ -0,0 +1,12 @@
+-- Collapse scoped assignments back into one global row per role.
+DELETE FROM user_roles a
+USING user_roles b
+WHERE a.user_id = b.user_id AND a.role_id = b.role_id
+  AND (a.scope_type, a.scope_id) > (b.scope_type, b.scope_id);
+
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_pkey;
+ALTER TABLE user_roles DROP CONSTRAINT IF EXISTS user_roles_scope_id_check;
+ALTER TABLE user_roles
+    DROP COLUMN IF EXISTS scope_id,
+    DROP COLUMN IF EXISTS scope_type;
+ALTER TABLE user_roles ADD PRIMARY KEY (user_id, role_id);
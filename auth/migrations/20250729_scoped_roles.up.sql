@@ -0,0 +1,25 @@
+-- Role assignments apply globally or within one university or group.
+-- scope_id is empty for global assignments so that it can be part of the key.
+ALTER TABLE user_roles
+    ADD COLUMN scope_type TEXT NOT NULL DEFAULT 'global'
+        CHECK (scope_type IN ('global', 'university', 'group')),
+    ADD COLUMN scope_id TEXT NOT NULL DEFAULT '';
+
+-- Existing teachers and students are scoped to what their profile says.
+UPDATE user_roles ur
+SET scope_type = 'university', scope_id = t.university_id::text
+FROM roles r, teachers t
+WHERE r.id = ur.role_id AND r.name = 'teacher'
+  AND t.user_id = ur.user_id AND t.university_id IS NOT NULL;
+
+UPDATE user_roles ur
+SET scope_type = 'group', scope_id = s.group_id::text
+FROM roles r, students s
+WHERE r.id = ur.role_id AND r.name = 'student'
+  AND s.user_id = ur.user_id AND s.group_id IS NOT NULL;
+
+ALTER TABLE user_roles
+    ADD CONSTRAINT user_roles_scope_id_check CHECK ((scope_type = 'global') = (scope_id = ''));
+
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_pkey;
+ALTER TABLE user_roles ADD PRIMARY KEY (user_id, role_id, scope_type, scope_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS user_roles_expires_at_idx;
+
+ALTER TABLE user_roles
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS granted_by,
+    DROP COLUMN IF EXISTS granted_at;
@@ -0,0 +1,8 @@
+-- Who granted a role assignment and when, and until when it holds. Existing
+-- assignments count as granted at migration time by nobody in particular.
+ALTER TABLE user_roles
+    ADD COLUMN granted_at TIMESTAMP NOT NULL DEFAULT now(),
+    ADD COLUMN granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN expires_at TIMESTAMP;
+
+CREATE INDEX user_roles_expires_at_idx ON user_roles (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'authz:check';
+
+DROP TABLE IF EXISTS teacher_groups;
@@ -0,0 +1,18 @@
+-- Groups a teacher teaches. Teaching a group grants the permissions of the
+-- teacher role on that group and its students.
+CREATE TABLE teacher_groups (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_id UUID NOT NULL,
+    assigned_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, group_id)
+);
+
+CREATE INDEX teacher_groups_group_id_idx ON teacher_groups (group_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('authz:check', 'Ask for authorization decisions on behalf of other users');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.name = 'authz:check';
@@ -0,0 +1,9 @@
+-- Admins may obtain short-lived, read-only access tokens of other users, see
+-- POST /admin/users/{id}/impersonate.
+INSERT INTO permissions (name, description) VALUES
+    ('users:impersonate', 'Act as another user with a short-lived access token');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.name = 'users:impersonate';
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'roles:approve';
+
+DROP TABLE IF EXISTS role_requests;
@@ -0,0 +1,29 @@
+-- Self-service requests for the student or teacher role, carrying the same
+-- attributes as an activation key. Approval activates the role.
+CREATE TABLE role_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('student', 'teacher')),
+    university_id UUID NOT NULL,
+    group_id UUID,
+    enrollment_year INTEGER,
+    degree TEXT,
+    comment TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    review_comment TEXT,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- One open request per user and role.
+CREATE UNIQUE INDEX role_requests_pending_idx ON role_requests (user_id, role) WHERE status = 'pending';
+CREATE INDEX role_requests_university_id_idx ON role_requests (university_id, status);
+
+INSERT INTO permissions (name, description) VALUES
+    ('roles:approve', 'Approve or reject requests for the student or teacher role');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.name = 'roles:approve';
@@ -0,0 +1,13 @@
+-- Activation keys handed out, by admins or by teachers for their groups.
+-- Teacher quotas are counted here.
+CREATE TABLE activation_key_issuances (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    key_id TEXT NOT NULL,
+    issued_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    role TEXT NOT NULL,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    issued_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX activation_key_issuances_issued_by_idx ON activation_key_issuances (issued_by, issued_at);
+CREATE UNIQUE INDEX activation_key_issuances_key_id_idx ON activation_key_issuances (key_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS username_history_username_canonical_idx;
+CREATE INDEX IF NOT EXISTS username_history_username_idx ON username_history (username, reserved_until);
+
+ALTER TABLE username_history DROP COLUMN IF EXISTS username_canonical;
@@ -0,0 +1,10 @@
+-- Reservations are matched in canonical form, like the usernames themselves.
+-- Storing it lets the lookup use an index.
+ALTER TABLE username_history ADD COLUMN username_canonical TEXT;
+
+UPDATE username_history SET username_canonical = lower(normalize(username, NFKC));
+
+ALTER TABLE username_history ALTER COLUMN username_canonical SET NOT NULL;
+
+DROP INDEX IF EXISTS username_history_username_idx;
+CREATE INDEX username_history_username_canonical_idx ON username_history (username_canonical, reserved_until);
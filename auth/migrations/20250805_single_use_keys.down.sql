@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS activation_key_redemptions_key_id_key;
+CREATE INDEX IF NOT EXISTS activation_key_redemptions_key_id_idx ON activation_key_redemptions (key_id);
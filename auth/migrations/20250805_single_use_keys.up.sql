@@ -0,0 +1,12 @@
+-- Activation keys can be redeemed once. Keys redeemed more than once before
+-- this constraint keep their first redemption, the later ones lose the key ID.
+UPDATE activation_key_redemptions r
+SET key_id = NULL
+WHERE EXISTS (
+    SELECT 1 FROM activation_key_redemptions first
+    WHERE first.key_id = r.key_id
+      AND (first.redeemed_at, first.id) < (r.redeemed_at, r.id)
+);
+
+DROP INDEX IF EXISTS activation_key_redemptions_key_id_idx;
+CREATE UNIQUE INDEX activation_key_redemptions_key_id_key ON activation_key_redemptions (key_id);